@@ -1,79 +1,351 @@
 package datatype
 
 import (
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strconv"
 )
 
+// 未知选项代码
+type ChoiceError struct {
+	// 枚举名称, ChoiceType无名称时为空
+	Enum string
+	Code int64
+	// 字符串代码, 按字符串查找时不为空
+	Key string
+}
+
+func (e *ChoiceError) Error() string {
+	code := strconv.FormatInt(e.Code, 10)
+
+	if e.Key != "" {
+		code = strconv.Quote(e.Key)
+	}
+
+	if e.Enum == "" {
+		return fmt.Sprintf("%s %s", ErrChoiceUnknown, code)
+	}
+
+	return fmt.Sprintf("%s: %s %s", ErrChoiceUnknown, e.Enum, code)
+}
+
+func (e *ChoiceError) Is(target error) bool {
+	return target == ErrChoiceUnknown
+}
+
+type ChoiceConfig struct {
+	// 严格模式, 未知代码不再回退为第一个选项, UnmarshalJSON及Scan返回*ChoiceError
+	Strict bool
+	// 默认语言, 上下文未设置语言时用于MarshalJSON及String
+	Locale string
+	// 原文(Choice.Value)的语言, 如zh, 匹配时直接返回Value, 不再查找优先级更低的语言
+	SourceLocale string
+	// 翻译目录
+	Catalog ChoiceCatalog
+}
+
+var ChoiceOptions = ChoiceConfig{}
+
+// 选项在数据库中的存储方式
+type ChoiceStorage int
+
+const (
+	// 保存整数代码
+	ChoiceStoreCode ChoiceStorage = iota
+	// 保存字符串代码
+	ChoiceStoreKey
+)
+
+// 选项
+//
+// 不兼容变更: Labels及Children使Choice不再可比较, 不能使用==比较或作为map的键,
+// 比较选项使用Equal, 作为键时使用Code或Key
 type Choice struct {
-	Code  uint8  `json:"code" validate:"required"`
-	Value string `json:"value"`
+	Code int64 `json:"code" validate:"required"`
+	// 字符串代码, 如ACTIVE
+	Key        string `json:"key,omitempty"`
+	Value      string `json:"value"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	// 多语言标签, 如{"en": "Pending"}
+	Labels map[string]string `json:"-"`
+	// 下级选项, 用于ChoicePath
+	Children []Choice `json:"-"`
+}
+
+// Equal 代码及字符串代码是否相同, 不比较标签
+func (c Choice) Equal(other Choice) bool {
+	return c.Code == other.Code && c.Key == other.Key
+}
+
+// Label 根据上下文中的语言获取标签
+func (c Choice) Label(ctx context.Context) string {
+	return choiceLabel(c, LocalesFrom(ctx))
+}
+
+// Localize 将Value替换为上下文语言的标签
+func (c Choice) Localize(ctx context.Context) Choice {
+	c.Value = c.Label(ctx)
+
+	return c
+}
+
+// lookup 根据代码或字符串代码在选项中查找, 空字符串代码不匹配任何选项
+func (c Choice) lookup(meta []Choice, byKey bool) (Choice, bool) {
+	if byKey && c.Key == "" {
+		return Choice{}, false
+	}
+
+	for _, choice := range meta {
+		if byKey && choice.Key == c.Key || !byKey && choice.Code == c.Code {
+			return choice, true
+		}
+	}
+
+	return Choice{}, false
+}
+
+func (c Choice) String() string {
+	label := c.Label(context.Background())
+
+	if c.Key != "" {
+		return fmt.Sprintf("{%s %s}", c.Key, label)
+	}
+
+	return fmt.Sprintf("{%d %s}", c.Code, label)
+}
+
+// parseChoice 解析数据库值, 整数为代码, 非数字字符串为字符串代码
+func parseChoice(value any) (Choice, bool, error) {
+	switch v := value.(type) {
+	case int64:
+		return Choice{Code: v}, false, nil
+	case int:
+		return Choice{Code: int64(v)}, false, nil
+	case int32:
+		return Choice{Code: int64(v)}, false, nil
+	case []byte:
+		return parseChoice(string(v))
+	case string:
+		if code, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return Choice{Code: code}, false, nil
+		}
+
+		return Choice{Key: v}, true, nil
+	}
+
+	return Choice{}, false, fmt.Errorf("datatype: cannot scan %T into Choice", value)
+}
+
+// parseChoiceJSON 解析代码、字符串代码或选项对象
+func parseChoiceJSON(data []byte) (Choice, bool, error) {
+	var choice Choice
+
+	if err := json.Unmarshal(data, &choice.Code); err == nil {
+		return choice, false, nil
+	}
+
+	var key string
+
+	if err := json.Unmarshal(data, &key); err == nil {
+		return parseChoice(key)
+	}
+
+	if err := json.Unmarshal(data, &choice); err != nil {
+		return choice, false, err
+	}
+
+	return choice, choice.Key != "", nil
 }
 
 type ChoiceType struct {
 	Choice Choice `json:"choice" validate:"required"`
 	Meta   []Choice
+	// 存储方式
+	Storage ChoiceStorage
+	// 状态变更图, 为nil时不限制变更
+	Graph *ChoiceGraph
+
+	// 从数据库读取的选项, 用于校验状态变更
+	origin Choice
+	loaded bool
 }
 
-// Update
-func (c *ChoiceType) Update(code uint8) bool {
-	ok := false
+// Update 未知代码时宽松模式回退为第一个选项, 严格模式保持原值
+func (c *ChoiceType) Update(code int64) bool {
+	return c.Set(code) == nil
+}
 
-	if len(c.Meta) > 0 {
-		for _, meta := range c.Meta {
-			if meta.Code == code {
-				c.Choice = meta
-				ok = true
+// Set 未知代码时返回*ChoiceError, 宽松模式下仍会回退为第一个选项
+func (c *ChoiceType) Set(code int64) error {
+	return c.set(Choice{Code: code}, false)
+}
 
-				break
-			}
-		}
+// SetKey 根据字符串代码设置
+func (c *ChoiceType) SetKey(key string) error {
+	return c.set(Choice{Key: key}, true)
+}
 
-		if !ok {
-			c.Choice = c.Meta[0]
-		}
-	} else {
-		c.Choice = Choice{Code: code}
-		ok = true
+// Transition 按状态变更图变更为to, 非法变更或守卫拒绝时返回*ChoiceTransitionError
+//
+// 从数据库读取的ChoiceType没有Graph及Meta, 此时返回ErrChoiceGraphMissing而不是放行任何变更;
+// 需要在读取后校验变更时使用Enum[T], 或通过RegisterChoiceTransitions及enum标签在保存时校验
+func (c *ChoiceType) Transition(to int64) error {
+	if c.Graph == nil && len(c.Meta) == 0 {
+		return ErrChoiceGraphMissing
+	}
+
+	choice, err := c.choiceResolve(to)
+
+	if err != nil {
+		return err
+	}
+
+	if err := c.Graph.Check(c.Choice, choice); err != nil {
+		return err
+	}
+
+	c.Choice = choice
+
+	return nil
+}
+
+func (c *ChoiceType) set(ref Choice, byKey bool) error {
+	if len(c.Meta) == 0 {
+		c.Choice = ref
+
+		return nil
+	}
+
+	if choice, ok := ref.lookup(c.Meta, byKey); ok {
+		c.Choice = choice
+
+		return nil
+	}
+
+	if !ChoiceOptions.Strict {
+		c.Choice = c.Meta[0]
+	}
+
+	return &ChoiceError{Code: ref.Code, Key: ref.Key}
+}
+
+// update 宽松模式下忽略未知代码
+func (c *ChoiceType) update(ref Choice, byKey bool, err error) error {
+	if err == nil {
+		err = c.set(ref, byKey)
+	}
+
+	if err != nil && ChoiceOptions.Strict {
+		return err
 	}
 
-	return ok
+	return nil
 }
 
 // GORM
 func (c *ChoiceType) Scan(value any) error {
-	if v, ok := value.(int); ok {
-		c.Update(uint8(v))
+	if value == nil {
+		return nil
 	}
 
+	ref, byKey, err := parseChoice(value)
+
+	if err := c.update(ref, byKey, err); err != nil {
+		return err
+	}
+
+	// 读取到字符串代码时按字符串代码保存, 避免GORM创建的零值ChoiceType写回代码0
+	if byKey {
+		c.Storage = ChoiceStoreKey
+	}
+
+	c.origin, c.loaded = c.Choice, true
+
 	return nil
 }
 
 func (c ChoiceType) Value() (driver.Value, error) {
+	if c.Storage == ChoiceStoreKey {
+		return c.Choice.Key, nil
+	}
+
 	return c.Choice.Code, nil
 }
 
 // JSON
 func (c ChoiceType) MarshalJSON() ([]byte, error) {
-	return json.Marshal(c.Choice)
+	return c.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 标签使用上下文中的语言
+func (c ChoiceType) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	return json.Marshal(c.Choice.Localize(ctx))
 }
 
 func (c *ChoiceType) UnmarshalJSON(data []byte) error {
-	var choice Choice
+	choice, byKey, err := parseChoiceJSON(data)
 
-	if err := json.Unmarshal(data, &choice.Code); err != nil {
-		if err := json.Unmarshal(data, &choice); err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
 
-	c.Update(choice.Code)
-
-	return nil
+	return c.update(choice, byKey, nil)
 }
 
 // String
 func (c ChoiceType) String() string {
-	return fmt.Sprintf("{%d %s}", c.Choice.Code, c.Choice.Value)
+	return c.Choice.String()
+}
+
+// 状态变更
+func (c ChoiceType) choiceOrigin() (Choice, bool) {
+	return c.origin, c.loaded
+}
+
+func (c ChoiceType) choiceCurrent() Choice {
+	return c.Choice
+}
+
+func (c ChoiceType) choiceResolve(value any) (Choice, error) {
+	switch v := value.(type) {
+	case choiceTransitioner:
+		return v.choiceCurrent(), nil
+	case Choice:
+		return v, nil
+	}
+
+	ref, byKey, err := parseChoice(value)
+
+	if err != nil {
+		return Choice{}, err
+	}
+
+	if len(c.Meta) == 0 {
+		return ref, nil
+	}
+
+	if choice, ok := ref.lookup(c.Meta, byKey); ok {
+		return choice, nil
+	}
+
+	return Choice{}, &ChoiceError{Code: ref.Code, Key: ref.Key}
+}
+
+// choiceCheck Meta为空时Scan只保存了代码或字符串代码, 校验前在Meta中查找完整的选项
+func (c ChoiceType) choiceCheck(from Choice, to Choice) error {
+	return c.Graph.Check(c.choiceNormalize(from), c.choiceNormalize(to))
+}
+
+func (c ChoiceType) choiceNormalize(ref Choice) Choice {
+	if choice, ok := ref.lookup(c.Meta, ref.Key != ""); ok {
+		return choice
+	}
+
+	return ref
+}
+
+func (c *ChoiceType) choiceCommit() {
+	c.origin, c.loaded = c.Choice, true
 }
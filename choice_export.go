@@ -0,0 +1,287 @@
+package datatype
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// 导出的选项
+type choiceOption struct {
+	Code       int64             `json:"code"`
+	Key        string            `json:"key,omitempty"`
+	Value      string            `json:"value"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Deprecated bool              `json:"deprecated,omitempty"`
+	Children   []choiceOption    `json:"children,omitempty"`
+}
+
+// choiceOptions
+func choiceOptions(choices []Choice, locales []string) []choiceOption {
+	data := []choiceOption{}
+
+	for _, choice := range choices {
+		option := choiceOption{
+			Code:       choice.Code,
+			Key:        choice.Key,
+			Value:      choiceLabel(choice, locales),
+			Labels:     choice.Labels,
+			Deprecated: choice.Deprecated,
+		}
+
+		if len(choice.Children) > 0 {
+			option.Children = choiceOptions(choice.Children, locales)
+		}
+
+		data = append(data, option)
+	}
+
+	return data
+}
+
+// ---------------------------------------------------------
+//
+//  ChoiceEnumHandler
+//
+// ---------------------------------------------------------
+
+// 以JSON输出所有已注册的枚举, 标签语言由Accept-Language决定
+//
+//	http.Handle("/enums", datatype.ChoiceEnumHandler{})
+//
+//	GET /enums?name=order_status,user_status
+//	{"order_status": [{"code": 1, "value": "待支付", "labels": {"en": "Pending"}}, ...]}
+type ChoiceEnumHandler struct{}
+
+func (h ChoiceEnumHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	names := map[string]bool{}
+
+	for _, v := range strings.Split(r.URL.Query().Get("name"), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			names[v] = true
+		}
+	}
+
+	locales := LocalesFrom(WithAcceptLanguage(r.Context(), r.Header.Get("Accept-Language")))
+	data := map[string][]choiceOption{}
+
+	for _, enum := range ChoiceEnums() {
+		if len(names) == 0 || names[enum.Name] {
+			data[enum.Name] = choiceOptions(enum.Choices, locales)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Vary", "Accept-Language")
+
+	json.NewEncoder(w).Encode(data)
+}
+
+// ---------------------------------------------------------
+//
+//  Schema
+//
+// ---------------------------------------------------------
+
+// ChoiceEnumSchema 生成枚举代码的OpenAPI/JSON Schema定义
+//
+//	{"type": "integer", "enum": [1, 9], "x-enum-varnames": ["PENDING", "CLOSED"], "x-enum-deprecated": [9]}
+func ChoiceEnumSchema(enum *ChoiceEnum) map[string]any {
+	codes := []any{}
+	keys := []any{}
+	descriptions := []string{}
+	deprecated := []any{}
+
+	for _, choice := range enum.Choices {
+		codes = append(codes, choice.Code)
+
+		if choice.Key != "" {
+			keys = append(keys, choice.Key)
+		}
+
+		description := fmt.Sprintf("%d: %s", choice.Code, choice.Value)
+
+		if choice.Deprecated {
+			description += " (deprecated)"
+			deprecated = append(deprecated, choice.Code)
+		}
+
+		descriptions = append(descriptions, description)
+	}
+
+	data := map[string]any{
+		"type":                "integer",
+		"enum":                codes,
+		"description":         strings.Join(descriptions, ", "),
+		"x-enum-descriptions": descriptions,
+	}
+
+	if len(keys) == len(codes) {
+		data["x-enum-varnames"] = keys
+	}
+
+	if len(deprecated) > 0 {
+		data["x-enum-deprecated"] = deprecated
+	}
+
+	return data
+}
+
+// ChoiceEnumSchemas 所有已注册枚举的Schema定义, 可放入components.schemas
+func ChoiceEnumSchemas() map[string]any {
+	data := map[string]any{}
+
+	for _, enum := range ChoiceEnums() {
+		data[enum.Name] = ChoiceEnumSchema(enum)
+	}
+
+	return data
+}
+
+// choiceObjectSchema 选项对象, 与ChoiceType的JSON输出一致
+func choiceObjectSchema(enum *ChoiceEnum) map[string]any {
+	properties := map[string]any{
+		"code":       ChoiceEnumSchema(enum),
+		"value":      map[string]any{"type": "string"},
+		"deprecated": map[string]any{"type": "boolean"},
+	}
+
+	var keys []any
+
+	for _, choice := range enum.Choices {
+		if choice.Key != "" {
+			keys = append(keys, choice.Key)
+		}
+	}
+
+	if len(keys) > 0 {
+		properties["key"] = map[string]any{"type": "string", "enum": keys}
+	}
+
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"code", "value"},
+	}
+}
+
+// 通过注册表获取枚举定义的字段, 如Enum[T]
+type choiceEnumMeta interface {
+	Meta() (*ChoiceEnum, error)
+}
+
+// 包含多个选项的字段, 如ChoiceFlags、ChoicePath
+type choiceEnumList interface {
+	Codes() []int64
+}
+
+// 按层级保存选项的字段, 如ChoicePath
+type choiceEnumPath interface {
+	Leaf() (Choice, bool)
+}
+
+// choicePathEnum 路径中的选项可以来自任意层级, 代码取自整棵选项树, 重复的代码只保留第一个
+func choicePathEnum(enum *ChoiceEnum) *ChoiceEnum {
+	var choices []Choice
+
+	seen := map[int64]bool{}
+
+	var walk func(items []Choice)
+
+	walk = func(items []Choice) {
+		for _, choice := range items {
+			if !seen[choice.Code] {
+				seen[choice.Code] = true
+				choices = append(choices, choice)
+			}
+
+			walk(choice.Children)
+		}
+	}
+
+	walk(enum.Choices)
+
+	return &ChoiceEnum{Name: enum.Name, Choices: choices, Storage: enum.Storage}
+}
+
+// ChoiceSchemaOf 生成结构体中选项字段的Schema, 返回JSON字段名到Schema的映射
+//
+// Enum[T]、EnumFlags[T]、EnumPath[T]使用注册的枚举, ChoiceType、ChoiceFlags、ChoicePath及其他类型通过enum标签指定:
+//
+//	type Order struct {
+//		Status datatype.Enum[OrderStatus] `json:"status"`
+//		Source datatype.ChoiceType        `json:"source" gorm:"enum:order_source"`
+//	}
+func ChoiceSchemaOf(v any) map[string]any {
+	data := map[string]any{}
+
+	rt := reflect.TypeOf(v)
+
+	for rt != nil && rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return data
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		// 指针字段如*Enum[T]按元素类型处理, nil指针不能调用值方法
+		t := field.Type
+
+		if t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		zero := reflect.Zero(t).Interface()
+
+		var enum *ChoiceEnum
+
+		if meta, ok := zero.(choiceEnumMeta); ok {
+			enum, _ = meta.Meta()
+		} else if v, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["ENUM"]; ok {
+			enum, _ = LookupChoiceEnum(v)
+		}
+
+		if enum == nil {
+			continue
+		}
+
+		if _, ok := zero.(choiceEnumPath); ok {
+			data[name] = map[string]any{"type": "array", "items": choiceObjectSchema(choicePathEnum(enum))}
+		} else if _, ok := zero.(choiceEnumList); ok {
+			data[name] = map[string]any{"type": "array", "items": choiceObjectSchema(enum)}
+		} else {
+			data[name] = choiceObjectSchema(enum)
+		}
+	}
+
+	return data
+}
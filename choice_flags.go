@@ -0,0 +1,379 @@
+package datatype
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/bits"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ---------------------------------------------------------
+//
+//  ChoiceFlags
+//
+// ---------------------------------------------------------
+
+// 选项集合, 以选项代码为位序保存在一个整数中, 代码范围0-62
+//
+// Meta不会保存到数据库, 从数据库读取的值没有标签, 此时应使用EnumFlags[T]
+type ChoiceFlags struct {
+	Flags int64
+	Meta  []Choice
+}
+
+// NewChoiceFlags
+func NewChoiceFlags(meta []Choice, codes ...int64) (ChoiceFlags, error) {
+	f := ChoiceFlags{Meta: meta}
+
+	return f, f.Add(codes...)
+}
+
+// choiceFlag 代码对应的位
+func choiceFlag(code int64) (int64, bool) {
+	if code < 0 || code > 62 {
+		return 0, false
+	}
+
+	return 1 << code, true
+}
+
+// check 代码超出范围或不在选项中时返回*ChoiceError
+func (f ChoiceFlags) check(code int64) (int64, error) {
+	flag, ok := choiceFlag(code)
+
+	if ok && len(f.Meta) > 0 {
+		_, ok = Choice{Code: code}.lookup(f.Meta, false)
+	}
+
+	if !ok {
+		return 0, &ChoiceError{Code: code}
+	}
+
+	return flag, nil
+}
+
+// Add 添加选项
+func (f *ChoiceFlags) Add(codes ...int64) error {
+	for _, code := range codes {
+		flag, err := f.check(code)
+
+		if err != nil {
+			return err
+		}
+
+		f.Flags |= flag
+	}
+
+	return nil
+}
+
+// Remove 移除选项
+func (f *ChoiceFlags) Remove(codes ...int64) {
+	for _, code := range codes {
+		if flag, ok := choiceFlag(code); ok {
+			f.Flags &^= flag
+		}
+	}
+}
+
+// Has 是否包含所有选项
+func (f ChoiceFlags) Has(codes ...int64) bool {
+	for _, code := range codes {
+		if flag, ok := choiceFlag(code); !ok || f.Flags&flag == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Codes 包含的代码, 从小到大排列
+func (f ChoiceFlags) Codes() []int64 {
+	var data []int64
+
+	for v := uint64(f.Flags); v != 0; v &= v - 1 {
+		data = append(data, int64(bits.TrailingZeros64(v)))
+	}
+
+	return data
+}
+
+// Choices 包含的选项, Meta中不存在的代码只保留代码
+func (f ChoiceFlags) Choices() []Choice {
+	var data []Choice
+
+	for _, code := range f.Codes() {
+		choice, ok := Choice{Code: code}.lookup(f.Meta, false)
+
+		if !ok {
+			choice = Choice{Code: code}
+		}
+
+		data = append(data, choice)
+	}
+
+	return data
+}
+
+// GORM
+func (f ChoiceFlags) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "BIGINT"
+}
+
+func (f *ChoiceFlags) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		f.Flags = 0
+
+		return nil
+	case int64:
+		f.Flags = v
+
+		return nil
+	case []byte:
+		return f.Scan(string(v))
+	case string:
+		flags, err := strconv.ParseInt(v, 10, 64)
+
+		if err != nil {
+			return fmt.Errorf("datatype: cannot scan %q into ChoiceFlags", v)
+		}
+
+		f.Flags = flags
+
+		return nil
+	}
+
+	return fmt.Errorf("datatype: cannot scan %T into ChoiceFlags", value)
+}
+
+func (f ChoiceFlags) Value() (driver.Value, error) {
+	return f.Flags, nil
+}
+
+// JSON
+func (f ChoiceFlags) MarshalJSON() ([]byte, error) {
+	return f.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 序列化为选项列表, 标签使用上下文中的语言
+func (f ChoiceFlags) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	data := []Choice{}
+
+	for _, choice := range f.Choices() {
+		data = append(data, choice.Localize(ctx))
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON 接受代码、字符串代码或选项对象的列表, 未知选项在宽松模式下忽略
+func (f *ChoiceFlags) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	f.Flags = 0
+
+	for _, item := range items {
+		ref, byKey, err := parseChoiceJSON(item)
+
+		if err != nil {
+			return err
+		}
+
+		if byKey {
+			choice, ok := ref.lookup(f.Meta, true)
+
+			if !ok {
+				if ChoiceOptions.Strict {
+					return &ChoiceError{Key: ref.Key}
+				}
+
+				continue
+			}
+
+			ref = choice
+		}
+
+		if err := f.Add(ref.Code); err != nil && ChoiceOptions.Strict {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String
+func (f ChoiceFlags) String() string {
+	return fmt.Sprint(f.Choices())
+}
+
+// ChoiceFlagsQuery 查询包含所有选项的记录
+//
+//	db.Where(datatype.ChoiceFlagsQuery("permissions", PermRead, PermWrite)).Find(&users)
+func ChoiceFlagsQuery(column string, codes ...int64) clause.Expression {
+	var mask int64
+
+	for _, code := range codes {
+		flag, ok := choiceFlag(code)
+
+		if !ok {
+			return clause.Expr{SQL: "1 = 0"}
+		}
+
+		mask |= flag
+	}
+
+	return clause.Expr{SQL: "? & ? = ?", Vars: []any{clause.Column{Name: column}, mask, mask}}
+}
+
+// ChoiceFlagsAnyQuery 查询包含任一选项的记录
+func ChoiceFlagsAnyQuery(column string, codes ...int64) clause.Expression {
+	var mask int64
+
+	for _, code := range codes {
+		if flag, ok := choiceFlag(code); ok {
+			mask |= flag
+		}
+	}
+
+	return clause.Expr{SQL: "? & ? <> 0", Vars: []any{clause.Column{Name: column}, mask}}
+}
+
+// ---------------------------------------------------------
+//
+//  EnumFlags
+//
+// ---------------------------------------------------------
+
+// 基于注册表的选项集合, 选项由RegisterEnum[T]注册, 零值即可Scan及反序列化
+//
+//	Permissions datatype.EnumFlags[Permission]
+type EnumFlags[T any] struct {
+	Flags int64
+}
+
+// NewEnumFlags
+func NewEnumFlags[T any](codes ...int64) (EnumFlags[T], error) {
+	var f EnumFlags[T]
+
+	return f, f.Add(codes...)
+}
+
+// Meta 注册的枚举
+func (f EnumFlags[T]) Meta() (*ChoiceEnum, error) {
+	return enumMeta[T]()
+}
+
+// choiceFlags 使用注册的选项作为Meta
+func (f EnumFlags[T]) choiceFlags() (ChoiceFlags, error) {
+	enum, err := f.Meta()
+
+	if err != nil {
+		return ChoiceFlags{Flags: f.Flags}, err
+	}
+
+	return ChoiceFlags{Flags: f.Flags, Meta: enum.Choices}, nil
+}
+
+// Add 添加选项
+func (f *EnumFlags[T]) Add(codes ...int64) error {
+	flags, err := f.choiceFlags()
+
+	if err != nil {
+		return err
+	}
+
+	err = flags.Add(codes...)
+	f.Flags = flags.Flags
+
+	return err
+}
+
+// Remove 移除选项
+func (f *EnumFlags[T]) Remove(codes ...int64) {
+	flags := ChoiceFlags{Flags: f.Flags}
+	flags.Remove(codes...)
+	f.Flags = flags.Flags
+}
+
+// Has 是否包含所有选项
+func (f EnumFlags[T]) Has(codes ...int64) bool {
+	return ChoiceFlags{Flags: f.Flags}.Has(codes...)
+}
+
+// Codes 包含的代码, 从小到大排列
+func (f EnumFlags[T]) Codes() []int64 {
+	return ChoiceFlags{Flags: f.Flags}.Codes()
+}
+
+// Choices 包含的选项
+func (f EnumFlags[T]) Choices() []Choice {
+	flags, _ := f.choiceFlags()
+
+	return flags.Choices()
+}
+
+// GORM
+func (f EnumFlags[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "BIGINT"
+}
+
+func (f *EnumFlags[T]) Scan(value any) error {
+	flags := ChoiceFlags{}
+
+	if err := flags.Scan(value); err != nil {
+		return err
+	}
+
+	f.Flags = flags.Flags
+
+	return nil
+}
+
+func (f EnumFlags[T]) Value() (driver.Value, error) {
+	return f.Flags, nil
+}
+
+// JSON
+func (f EnumFlags[T]) MarshalJSON() ([]byte, error) {
+	return f.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 序列化为选项列表, 标签使用上下文中的语言
+func (f EnumFlags[T]) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	flags, _ := f.choiceFlags()
+
+	return flags.MarshalJSONContext(ctx)
+}
+
+// UnmarshalJSON 接受代码、字符串代码或选项对象的列表, 枚举未注册时返回错误
+func (f *EnumFlags[T]) UnmarshalJSON(data []byte) error {
+	flags, err := f.choiceFlags()
+
+	if err != nil {
+		return err
+	}
+
+	if err := flags.UnmarshalJSON(data); err != nil {
+		return err
+	}
+
+	f.Flags = flags.Flags
+
+	return nil
+}
+
+// String
+func (f EnumFlags[T]) String() string {
+	return fmt.Sprint(f.Choices())
+}
@@ -0,0 +1,229 @@
+package datatype
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 翻译目录, locale -> 原文(Choice.Value) -> 译文
+//
+//	{
+//		"en": {"待支付": "Pending", "已关闭": "Closed"}
+//	}
+type ChoiceCatalog map[string]map[string]string
+
+// Lookup 查找译文, locale不存在时尝试主语言(如en-US -> en)
+func (c ChoiceCatalog) Lookup(locale string, message string) (string, bool) {
+	for _, v := range localeCandidates(locale) {
+		for key, messages := range c {
+			if strings.EqualFold(normalizeLocale(key), v) {
+				if text, ok := messages[message]; ok && text != "" {
+					return text, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// Merge 合并目录, 已存在的译文被覆盖, 目录为nil时自动创建
+func (c *ChoiceCatalog) Merge(other ChoiceCatalog) {
+	if *c == nil {
+		*c = ChoiceCatalog{}
+	}
+
+	for locale, messages := range other {
+		if (*c)[locale] == nil {
+			(*c)[locale] = map[string]string{}
+		}
+
+		for k, v := range messages {
+			(*c)[locale][k] = v
+		}
+	}
+}
+
+// LoadChoiceCatalog 读取JSON格式的翻译目录
+func LoadChoiceCatalog(r io.Reader) (ChoiceCatalog, error) {
+	data := ChoiceCatalog{}
+
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// LoadChoiceCatalogFS 读取目录中的<locale>.json文件, 每个文件为原文到译文的映射
+//
+//	locales/en.json
+//	locales/ja.json
+func LoadChoiceCatalogFS(fsys fs.FS, dir string) (ChoiceCatalog, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := ChoiceCatalog{}
+
+	for _, file := range files {
+		content, err := fs.ReadFile(fsys, file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]string
+
+		if err := json.Unmarshal(content, &messages); err != nil {
+			return nil, err
+		}
+
+		data.Merge(ChoiceCatalog{strings.TrimSuffix(path.Base(file), ".json"): messages})
+	}
+
+	return data, nil
+}
+
+// ---------------------------------------------------------
+//
+//  Locale
+//
+// ---------------------------------------------------------
+
+type localeKey struct{}
+
+// WithLocale 设置上下文中的语言, 按优先级排列
+func WithLocale(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locales)
+}
+
+// WithAcceptLanguage 根据Accept-Language请求头设置上下文中的语言
+func WithAcceptLanguage(ctx context.Context, header string) context.Context {
+	return WithLocale(ctx, ParseAcceptLanguage(header)...)
+}
+
+// LocalesFrom 获取上下文中的语言, 未设置时使用ChoiceOptions.Locale
+func LocalesFrom(ctx context.Context) []string {
+	if ctx != nil {
+		if v, ok := ctx.Value(localeKey{}).([]string); ok && len(v) > 0 {
+			return v
+		}
+	}
+
+	if ChoiceOptions.Locale != "" {
+		return []string{ChoiceOptions.Locale}
+	}
+
+	return nil
+}
+
+// ParseAcceptLanguage 解析Accept-Language, 按权重从高到低返回语言
+//
+//	zh-CN,zh;q=0.9,en;q=0.8 -> [zh-CN zh en]
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		tag string
+		q   float64
+	}
+
+	var languages []language
+
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.TrimSpace(tag)
+
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+
+		for _, param := range strings.Split(params, ";") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(param), "="); ok && strings.TrimSpace(k) == "q" {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+					q = f
+				}
+			}
+		}
+
+		if q > 0 {
+			languages = append(languages, language{tag: tag, q: q})
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].q > languages[j].q
+	})
+
+	var data []string
+
+	for _, v := range languages {
+		data = append(data, v.tag)
+	}
+
+	return data
+}
+
+// normalizeLocale zh_CN -> zh-cn
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
+
+// localeCandidates zh-Hans-CN -> [zh-hans-cn zh-hans zh]
+func localeCandidates(locale string) []string {
+	locale = normalizeLocale(locale)
+
+	var data []string
+
+	for locale != "" {
+		data = append(data, locale)
+
+		i := strings.LastIndex(locale, "-")
+
+		if i < 0 {
+			break
+		}
+
+		locale = locale[:i]
+	}
+
+	return data
+}
+
+// choiceLabel 依次查找选项自带的标签、原文语言及ChoiceOptions.Catalog, 均不存在时返回Value
+func choiceLabel(choice Choice, locales []string) string {
+	source := normalizeLocale(ChoiceOptions.SourceLocale)
+
+	for _, locale := range locales {
+		for _, v := range localeCandidates(locale) {
+			for key, label := range choice.Labels {
+				if label != "" && normalizeLocale(key) == v {
+					return label
+				}
+			}
+		}
+
+		if source != "" {
+			for _, v := range localeCandidates(locale) {
+				if v == source {
+					return choice.Value
+				}
+			}
+		}
+
+		if text, ok := ChoiceOptions.Catalog.Lookup(locale, choice.Value); ok {
+			return text
+		}
+	}
+
+	return choice.Value
+}
@@ -0,0 +1,478 @@
+package datatype
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 路径分隔符
+const choicePathSeparator = "/"
+
+// 级联选项, 如省/市/区, 以代码路径保存(如11/1101/110101)
+//
+// Meta不会保存到数据库, 从数据库读取的路径无法校验且没有标签, 此时应使用EnumPath[T]
+type ChoicePath struct {
+	// 从根到末级的选项
+	Choices []Choice
+	// 树形选项, 由Choice.Children组成
+	Meta []Choice
+	// 存储方式, 字符串代码时保存字符串代码路径
+	Storage ChoiceStorage
+}
+
+// NewChoicePath
+func NewChoicePath(meta []Choice, codes ...int64) (ChoicePath, error) {
+	p := ChoicePath{Meta: meta}
+
+	return p, p.Update(codes...)
+}
+
+// Update 根据代码路径设置, 路径不存在时返回*ChoiceError且保持原值
+func (p *ChoicePath) Update(codes ...int64) error {
+	refs := make([]Choice, len(codes))
+
+	for i, code := range codes {
+		refs[i] = Choice{Code: code}
+	}
+
+	return p.set(refs)
+}
+
+// UpdateKeys 根据字符串代码路径设置
+func (p *ChoicePath) UpdateKeys(keys ...string) error {
+	refs := make([]Choice, len(keys))
+
+	for i, key := range keys {
+		if key == "" {
+			return &ChoiceError{}
+		}
+
+		refs[i] = Choice{Key: key}
+	}
+
+	return p.set(refs)
+}
+
+func (p *ChoicePath) set(refs []Choice) error {
+	choices, err := p.resolve(refs)
+
+	if err != nil {
+		return err
+	}
+
+	p.Choices = choices
+
+	return nil
+}
+
+// resolve 在树中逐级查找, 设置了Key的按字符串代码查找, 返回已找到的前缀及错误
+func (p ChoicePath) resolve(refs []Choice) ([]Choice, error) {
+	var choices []Choice
+
+	meta := p.Meta
+
+	for _, ref := range refs {
+		if len(p.Meta) == 0 {
+			choices = append(choices, ref)
+
+			continue
+		}
+
+		choice, ok := ref.lookup(meta, ref.Key != "")
+
+		if !ok {
+			return choices, &ChoiceError{Code: ref.Code, Key: ref.Key}
+		}
+
+		choices = append(choices, choice)
+		meta = choice.Children
+	}
+
+	return choices, nil
+}
+
+// update 宽松模式下保留有效的前缀, 用于外部输入
+func (p *ChoicePath) update(refs []Choice) error {
+	choices, err := p.resolve(refs)
+
+	if err != nil && ChoiceOptions.Strict {
+		return err
+	}
+
+	p.Choices = choices
+
+	return nil
+}
+
+// Valid 路径是否存在
+func (p ChoicePath) Valid() bool {
+	refs := make([]Choice, len(p.Choices))
+
+	for i, choice := range p.Choices {
+		refs[i] = Choice{Code: choice.Code}
+	}
+
+	_, err := p.resolve(refs)
+
+	return err == nil
+}
+
+// Leaf 末级选项
+func (p ChoicePath) Leaf() (Choice, bool) {
+	if len(p.Choices) == 0 {
+		return Choice{}, false
+	}
+
+	return p.Choices[len(p.Choices)-1], true
+}
+
+// Codes 代码路径
+func (p ChoicePath) Codes() []int64 {
+	data := make([]int64, len(p.Choices))
+
+	for i, choice := range p.Choices {
+		data[i] = choice.Code
+	}
+
+	return data
+}
+
+// Labels 标签路径, 使用上下文中的语言
+func (p ChoicePath) Labels(ctx context.Context) []string {
+	data := make([]string, len(p.Choices))
+
+	for i, choice := range p.Choices {
+		data[i] = choice.Label(ctx)
+	}
+
+	return data
+}
+
+// Path 保存到数据库的路径
+func (p ChoicePath) Path() string {
+	data := make([]string, len(p.Choices))
+
+	for i, choice := range p.Choices {
+		if p.Storage == ChoiceStoreKey {
+			data[i] = choice.Key
+		} else {
+			data[i] = strconv.FormatInt(choice.Code, 10)
+		}
+	}
+
+	return strings.Join(data, choicePathSeparator)
+}
+
+// parseChoicePath 11/1101 -> [{Code: 11} {Code: 1101}], 遇到空的层级时返回之前的部分及*ChoiceError
+func parseChoicePath(path string) ([]Choice, error) {
+	var refs []Choice
+
+	for _, v := range strings.Split(path, choicePathSeparator) {
+		if v == "" {
+			return refs, &ChoiceError{}
+		}
+
+		ref, _, _ := parseChoice(v)
+
+		refs = append(refs, ref)
+	}
+
+	return refs, nil
+}
+
+// GORM
+func (p ChoicePath) GormDataType() string {
+	return "string"
+}
+
+func (p ChoicePath) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres", "sqlite":
+		return "TEXT"
+	case "sqlserver":
+		return "NVARCHAR(255)"
+	default:
+		return "VARCHAR(255)"
+	}
+}
+
+// Scan 宽松模式下无法解析的层级原样保留, 保存时写回原路径; 含空层级的路径在两种模式下均返回错误
+func (p *ChoicePath) Scan(value any) error {
+	var path string
+
+	switch v := value.(type) {
+	case nil:
+		p.Choices = nil
+
+		return nil
+	case []byte:
+		path = string(v)
+	case string:
+		path = v
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into ChoicePath", value)
+	}
+
+	if path == "" {
+		p.Choices = nil
+
+		return nil
+	}
+
+	refs, err := parseChoicePath(path)
+
+	if err != nil {
+		return err
+	}
+
+	choices, err := p.resolve(refs)
+
+	if err != nil {
+		if ChoiceOptions.Strict {
+			return err
+		}
+
+		// 只保留有效前缀会在下次保存时截断数据库中的路径
+		choices = append(choices, refs[len(choices):]...)
+	}
+
+	p.Choices = choices
+
+	return nil
+}
+
+func (p ChoicePath) Value() (driver.Value, error) {
+	if len(p.Choices) == 0 {
+		return nil, nil
+	}
+
+	return p.Path(), nil
+}
+
+// JSON
+func (p ChoicePath) MarshalJSON() ([]byte, error) {
+	return p.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 序列化为从根到末级的选项列表, 标签使用上下文中的语言
+func (p ChoicePath) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	data := []Choice{}
+
+	for _, choice := range p.Choices {
+		data = append(data, choice.Localize(ctx))
+	}
+
+	return json.Marshal(data)
+}
+
+// UnmarshalJSON 接受路径字符串, 或代码、字符串代码、选项对象的列表
+func (p *ChoicePath) UnmarshalJSON(data []byte) error {
+	var path string
+
+	if err := json.Unmarshal(data, &path); err == nil {
+		return p.Scan(path)
+	}
+
+	var items []json.RawMessage
+
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	var refs []Choice
+
+	for _, item := range items {
+		ref, byKey, err := parseChoiceJSON(item)
+
+		if err != nil {
+			return err
+		}
+
+		// 空字符串代码不能按代码0查找
+		if byKey && ref.Key == "" {
+			if ChoiceOptions.Strict {
+				return &ChoiceError{}
+			}
+
+			break
+		}
+
+		refs = append(refs, ref)
+	}
+
+	return p.update(refs)
+}
+
+// String
+func (p ChoicePath) String() string {
+	return strings.Join(p.Labels(context.Background()), " / ")
+}
+
+// ChoicePathQuery 查询路径及其下级, 如ChoicePathQuery("region", 11)匹配11及11/1101/110101
+func ChoicePathQuery(column string, codes ...int64) clause.Expression {
+	data := make([]string, len(codes))
+
+	for i, code := range codes {
+		data[i] = strconv.FormatInt(code, 10)
+	}
+
+	path := strings.Join(data, choicePathSeparator)
+
+	return clause.Or(
+		clause.Eq{Column: clause.Column{Name: column}, Value: path},
+		clause.Like{Column: clause.Column{Name: column}, Value: path + choicePathSeparator + "%"},
+	)
+}
+
+// ---------------------------------------------------------
+//
+//  EnumPath
+//
+// ---------------------------------------------------------
+
+// 基于注册表的级联选项, 树形选项由RegisterEnum[T]注册, 零值即可Scan及反序列化
+//
+//	Region datatype.EnumPath[Region]
+type EnumPath[T any] struct {
+	// 从根到末级的选项
+	Choices []Choice
+}
+
+// NewEnumPath
+func NewEnumPath[T any](codes ...int64) (EnumPath[T], error) {
+	var p EnumPath[T]
+
+	return p, p.Update(codes...)
+}
+
+// Meta 注册的枚举
+func (p EnumPath[T]) Meta() (*ChoiceEnum, error) {
+	return enumMeta[T]()
+}
+
+// choicePath 使用注册的选项及存储方式
+func (p EnumPath[T]) choicePath() (ChoicePath, error) {
+	enum, err := p.Meta()
+
+	if err != nil {
+		return ChoicePath{Choices: p.Choices}, err
+	}
+
+	return ChoicePath{Choices: p.Choices, Meta: enum.Choices, Storage: enum.Storage}, nil
+}
+
+// apply 执行ChoicePath的修改并保存结果, 枚举未注册时返回错误
+func (p *EnumPath[T]) apply(fn func(path *ChoicePath) error) error {
+	path, err := p.choicePath()
+
+	if err != nil {
+		return err
+	}
+
+	if err := fn(&path); err != nil {
+		return err
+	}
+
+	p.Choices = path.Choices
+
+	return nil
+}
+
+// Update 根据代码路径设置, 路径不存在时返回*ChoiceError且保持原值
+func (p *EnumPath[T]) Update(codes ...int64) error {
+	return p.apply(func(path *ChoicePath) error {
+		return path.Update(codes...)
+	})
+}
+
+// UpdateKeys 根据字符串代码路径设置
+func (p *EnumPath[T]) UpdateKeys(keys ...string) error {
+	return p.apply(func(path *ChoicePath) error {
+		return path.UpdateKeys(keys...)
+	})
+}
+
+// Valid 路径是否存在
+func (p EnumPath[T]) Valid() bool {
+	path, err := p.choicePath()
+
+	return err == nil && path.Valid()
+}
+
+// Leaf 末级选项
+func (p EnumPath[T]) Leaf() (Choice, bool) {
+	return ChoicePath{Choices: p.Choices}.Leaf()
+}
+
+// Codes 代码路径
+func (p EnumPath[T]) Codes() []int64 {
+	return ChoicePath{Choices: p.Choices}.Codes()
+}
+
+// Labels 标签路径, 使用上下文中的语言
+func (p EnumPath[T]) Labels(ctx context.Context) []string {
+	return ChoicePath{Choices: p.Choices}.Labels(ctx)
+}
+
+// Path 保存到数据库的路径
+func (p EnumPath[T]) Path() string {
+	path, _ := p.choicePath()
+
+	return path.Path()
+}
+
+// GORM
+func (p EnumPath[T]) GormDataType() string {
+	return "string"
+}
+
+func (p EnumPath[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return ChoicePath{}.GormDBDataType(db, field)
+}
+
+// Scan 在注册的选项中校验路径, 宽松模式下保留有效的前缀
+func (p *EnumPath[T]) Scan(value any) error {
+	return p.apply(func(path *ChoicePath) error {
+		return path.Scan(value)
+	})
+}
+
+func (p EnumPath[T]) Value() (driver.Value, error) {
+	path, err := p.choicePath()
+
+	if err != nil && len(p.Choices) > 0 {
+		return nil, err
+	}
+
+	return path.Value()
+}
+
+// JSON
+func (p EnumPath[T]) MarshalJSON() ([]byte, error) {
+	return p.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 序列化为从根到末级的选项列表, 标签使用上下文中的语言
+func (p EnumPath[T]) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	return ChoicePath{Choices: p.Choices}.MarshalJSONContext(ctx)
+}
+
+// UnmarshalJSON 接受路径字符串, 或代码、字符串代码、选项对象的列表
+func (p *EnumPath[T]) UnmarshalJSON(data []byte) error {
+	return p.apply(func(path *ChoicePath) error {
+		return path.UnmarshalJSON(data)
+	})
+}
+
+// String
+func (p EnumPath[T]) String() string {
+	return ChoicePath{Choices: p.Choices}.String()
+}
@@ -0,0 +1,55 @@
+package datatype
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 宽松模式下无法解析的路径原样写回
+func TestChoicePathScanKeepsUnknownPath(t *testing.T) {
+	meta := []Choice{{Code: 11, Value: "北京", Children: []Choice{{Code: 1101, Value: "市辖区"}}}}
+
+	for _, path := range []string{"11/1101", "11/9999", "12/1201"} {
+		p := ChoicePath{Meta: meta}
+
+		if err := p.Scan(path); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _ := p.Value(); got != path {
+			t.Errorf("Scan(%q) then Value() = %v", path, got)
+		}
+
+		if want := path == "11/1101"; p.Valid() != want {
+			t.Errorf("Scan(%q) Valid() = %v, want %v", path, p.Valid(), want)
+		}
+	}
+
+	p := ChoicePath{Meta: meta}
+
+	if err := p.Scan("11//1101"); err == nil {
+		t.Error("path with an empty segment accepted")
+	}
+}
+
+type choicePathRegion struct{}
+
+var _ = RegisterEnum[choicePathRegion]("choice_path_region",
+	Choice{Code: 11, Value: "北京", Children: []Choice{{Code: 1101, Value: "市辖区"}}},
+	Choice{Code: 12, Value: "天津", Children: []Choice{{Code: 1201, Value: "市辖区"}}},
+)
+
+type choicePathAddress struct {
+	Region EnumPath[choicePathRegion] `json:"region"`
+}
+
+// 路径Schema包含各层级的代码
+func TestChoicePathSchema(t *testing.T) {
+	data := ChoiceSchemaOf(choicePathAddress{})
+	items := data["region"].(map[string]any)["items"].(map[string]any)
+	code := items["properties"].(map[string]any)["code"].(map[string]any)
+
+	if want := []any{int64(11), int64(1101), int64(12), int64(1201)}; !reflect.DeepEqual(code["enum"], want) {
+		t.Errorf("code enum %v, want %v", code["enum"], want)
+	}
+}
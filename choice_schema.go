@@ -0,0 +1,229 @@
+package datatype
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrChoiceStorage = errors.New("datatype: ChoiceType requires an enum stored as codes, use Enum[T] for key storage")
+)
+
+// 选项列的数据库类型
+type ChoiceColumn int
+
+const (
+	// 普通整数或字符串列
+	ChoiceColumnPlain ChoiceColumn = iota
+	// 原生枚举, Postgres使用CREATE TYPE ... AS ENUM, MySQL使用ENUM(...), 其他数据库退化为CHECK约束
+	ChoiceColumnEnum
+	// CHECK约束, 限制列值为选项代码
+	ChoiceColumnCheck
+)
+
+// ColumnAs 设置列的数据库类型, 应在注册时调用, 原生枚举以字符串代码保存
+//
+//	var _ = datatype.RegisterEnum[OrderStatus]("order_status", choices...).ColumnAs(datatype.ChoiceColumnEnum)
+func (e *ChoiceEnum) ColumnAs(column ChoiceColumn) *ChoiceEnum {
+	e.Column = column
+
+	if column == ChoiceColumnEnum {
+		e.Storage = ChoiceStoreKey
+	}
+
+	return e
+}
+
+// values 列允许的值, 已废弃的选项仍然保留
+func (e *ChoiceEnum) values() []string {
+	var data []string
+
+	for _, choice := range e.Choices {
+		if e.Storage == ChoiceStoreKey {
+			data = append(data, choice.Key)
+		} else {
+			data = append(data, strconv.FormatInt(choice.Code, 10))
+		}
+	}
+
+	return data
+}
+
+// literals 引号包裹的值列表
+func (e *ChoiceEnum) literals() string {
+	data := e.values()
+
+	if e.Storage == ChoiceStoreKey {
+		for i, v := range data {
+			data[i] = choiceQuoteLiteral(v)
+		}
+	}
+
+	return strings.Join(data, ", ")
+}
+
+// choiceQuoteLiteral SQL字符串字面量
+func choiceQuoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// choiceDBDataType 根据枚举定义生成列类型, 返回空字符串时使用GORM默认类型
+func choiceDBDataType(db *gorm.DB, field *schema.Field, enum *ChoiceEnum) string {
+	if enum == nil {
+		return ""
+	}
+
+	dialect := db.Dialector.Name()
+
+	var base string
+
+	if enum.Storage == ChoiceStoreKey {
+		switch dialect {
+		case "postgres", "sqlite":
+			base = "TEXT"
+		case "sqlserver":
+			base = "NVARCHAR(64)"
+		default:
+			base = "VARCHAR(64)"
+		}
+	} else {
+		base = "BIGINT"
+	}
+
+	switch enum.Column {
+	case ChoiceColumnEnum:
+		switch dialect {
+		case "postgres":
+			return db.Statement.Quote(enum.Name)
+		case "mysql":
+			return "ENUM(" + enum.literals() + ")"
+		}
+
+		fallthrough
+	case ChoiceColumnCheck:
+		return base + " CHECK (" + db.Statement.Quote(field.DBName) + " IN (" + enum.literals() + "))"
+	}
+
+	if enum.Storage == ChoiceStoreKey {
+		return base
+	}
+
+	return ""
+}
+
+// GORM, ChoiceType没有类型级的选项定义, 需要通过enum标签指定已注册的枚举
+//
+//	Status datatype.ChoiceType `gorm:"enum:order_status"`
+//
+// ChoiceType的Value按值自身的Storage保存, 无法保证写入字符串代码,
+// 因此标签只能指定以代码保存的枚举, 字符串代码或原生枚举的列应使用Enum[T], 否则迁移时panic
+func (c ChoiceType) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if name, ok := field.TagSettings["ENUM"]; ok {
+		if enum, ok := LookupChoiceEnum(name); ok {
+			if enum.Storage == ChoiceStoreKey {
+				panic(fmt.Errorf("%w: %s.%s uses enum %s", ErrChoiceStorage, field.Schema.Name, field.Name, enum.Name))
+			}
+
+			return choiceDBDataType(db, field, enum)
+		}
+	}
+
+	return ""
+}
+
+func (e Enum[T]) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	enum, _ := LookupEnum[T]()
+
+	return choiceDBDataType(db, field, enum)
+}
+
+// ---------------------------------------------------------
+//
+//  Migrate
+//
+// ---------------------------------------------------------
+
+// MigrateChoiceEnums 创建Postgres原生枚举类型并追加新增的值, 应在AutoMigrate之前调用
+//
+// 已存在的值不会删除或重排, 新值按选项顺序插入到前一个值之后;
+// 未指定枚举时处理所有ColumnAs(ChoiceColumnEnum)的已注册枚举, 非Postgres数据库不做处理
+func MigrateChoiceEnums(db *gorm.DB, enums ...*ChoiceEnum) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	if len(enums) == 0 {
+		for _, enum := range ChoiceEnums() {
+			if enum.Column == ChoiceColumnEnum {
+				enums = append(enums, enum)
+			}
+		}
+	}
+
+	// ALTER TYPE ... ADD VALUE在Postgres 12之前不能在事务中执行
+	tx := db.Session(&gorm.Session{NewDB: true, SkipDefaultTransaction: true})
+
+	for _, enum := range enums {
+		if err := migrateChoiceEnum(tx, enum); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrateChoiceEnum(db *gorm.DB, enum *ChoiceEnum) error {
+	name := db.Statement.Quote(enum.Name)
+
+	var exists bool
+
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_type WHERE typname = ?)", enum.Name).Scan(&exists).Error; err != nil {
+		return err
+	}
+
+	if !exists {
+		return db.Exec("CREATE TYPE " + name + " AS ENUM (" + enum.literals() + ")").Error
+	}
+
+	var labels []string
+
+	err := db.Raw(
+		"SELECT e.enumlabel FROM pg_enum e JOIN pg_type t ON t.oid = e.enumtypid WHERE t.typname = ? ORDER BY e.enumsortorder",
+		enum.Name,
+	).Scan(&labels).Error
+
+	if err != nil {
+		return err
+	}
+
+	current := map[string]bool{}
+
+	for _, v := range labels {
+		current[v] = true
+	}
+
+	prev := ""
+
+	for _, v := range enum.values() {
+		if !current[v] {
+			sql := "ALTER TYPE " + name + " ADD VALUE IF NOT EXISTS " + choiceQuoteLiteral(v)
+
+			if prev != "" {
+				sql += " AFTER " + choiceQuoteLiteral(prev)
+			}
+
+			if err := db.Exec(sql).Error; err != nil {
+				return err
+			}
+		}
+
+		prev = v
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package datatype
+
+import (
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"gorm.io/gorm/utils/tests"
+)
+
+// GORM读取时使用零值ChoiceType, 保存时应写回读取到的值
+func TestChoiceTypeScanValueRoundTrip(t *testing.T) {
+	meta := []Choice{{Code: 1, Key: "ACTIVE", Value: "启用"}, {Code: 2, Key: "DISABLED", Value: "停用"}}
+
+	cases := []struct {
+		meta  []Choice
+		value any
+		want  driver.Value
+	}{
+		{nil, "ACTIVE", "ACTIVE"},
+		{nil, []byte("ACTIVE"), "ACTIVE"},
+		{nil, int64(1), int64(1)},
+		{nil, "1", int64(1)},
+		{meta, "DISABLED", "DISABLED"},
+		{meta, int64(2), int64(2)},
+	}
+
+	for _, c := range cases {
+		v := ChoiceType{Meta: c.meta}
+
+		if err := v.Scan(c.value); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := v.Value()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.want {
+			t.Errorf("Scan(%v) then Value() = %#v, want %#v", c.value, got, c.want)
+		}
+	}
+}
+
+type choiceSchemaStatus struct{}
+
+var _ = RegisterEnum[choiceSchemaStatus]("choice_schema_status", Choice{Code: 1, Key: "ACTIVE", Value: "启用"}).ColumnAs(ChoiceColumnEnum)
+
+type choiceSchemaUser struct {
+	ID     int64
+	Status ChoiceType `gorm:"enum:choice_schema_status"`
+}
+
+// 字符串代码保存的枚举不能用于ChoiceType, 否则Value写入的代码会被原生枚举列拒绝
+func TestChoiceTypeKeyEnumColumn(t *testing.T) {
+	s, err := schema.Parse(&choiceSchemaUser{}, &sync.Map{}, schema.NamingStrategy{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err, _ := recover().(error); !errors.Is(err, ErrChoiceStorage) {
+			t.Errorf("got %v, want %v", err, ErrChoiceStorage)
+		}
+	}()
+
+	ChoiceType{}.GormDBDataType(db, s.LookUpField("Status"))
+}
@@ -0,0 +1,354 @@
+package datatype
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrChoiceTransition   = errors.New("datatype: illegal choice transition")
+	ErrChoiceGraphCodes   = errors.New("datatype: choice graph requires distinct choice codes")
+	ErrChoiceGraphMissing = errors.New("datatype: choice transition requires a graph or meta")
+)
+
+// 非法的状态变更
+type ChoiceTransitionError struct {
+	From Choice
+	To   Choice
+	// 守卫返回的错误, 未定义该变更时为nil
+	Err error
+}
+
+func (e *ChoiceTransitionError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s %s -> %s: %v", ErrChoiceTransition, e.From, e.To, e.Err)
+	}
+
+	return fmt.Sprintf("%s %s -> %s", ErrChoiceTransition, e.From, e.To)
+}
+
+func (e *ChoiceTransitionError) Is(target error) bool {
+	return target == ErrChoiceTransition
+}
+
+func (e *ChoiceTransitionError) Unwrap() error {
+	return e.Err
+}
+
+// 状态变更守卫, 返回错误时拒绝变更
+type ChoiceGuard func(from Choice, to Choice) error
+
+// 状态变更图, 以选项代码为节点, 字符串代码存储的选项也需要设置互不相同的Code
+//
+//	graph := datatype.NewChoiceGraph().
+//		Allow(OrderPending, OrderPaid, OrderClosed).
+//		Allow(OrderPaid, OrderShipped).
+//		Guard(OrderPaid, OrderShipped, func(from, to datatype.Choice) error { ... })
+type ChoiceGraph struct {
+	edges  map[int64]map[int64][]ChoiceGuard
+	guards []ChoiceGuard
+}
+
+// NewChoiceGraph
+func NewChoiceGraph() *ChoiceGraph {
+	return &ChoiceGraph{edges: map[int64]map[int64][]ChoiceGuard{}}
+}
+
+// Allow 允许from变更为to
+func (g *ChoiceGraph) Allow(from int64, to ...int64) *ChoiceGraph {
+	if g.edges[from] == nil {
+		g.edges[from] = map[int64][]ChoiceGuard{}
+	}
+
+	for _, v := range to {
+		if _, ok := g.edges[from][v]; !ok {
+			g.edges[from][v] = nil
+		}
+	}
+
+	return g
+}
+
+// Guard 允许from变更为to, 并在变更时执行守卫
+func (g *ChoiceGraph) Guard(from int64, to int64, guard ChoiceGuard) *ChoiceGraph {
+	g.Allow(from, to)
+	g.edges[from][to] = append(g.edges[from][to], guard)
+
+	return g
+}
+
+// GuardAll 所有变更均执行的守卫
+func (g *ChoiceGraph) GuardAll(guard ChoiceGuard) *ChoiceGraph {
+	g.guards = append(g.guards, guard)
+
+	return g
+}
+
+// Can 是否允许变更, 不执行守卫
+func (g *ChoiceGraph) Can(from int64, to int64) bool {
+	if g == nil || from == to {
+		return true
+	}
+
+	_, ok := g.edges[from][to]
+
+	return ok
+}
+
+// Next 允许变更到的代码
+func (g *ChoiceGraph) Next(from int64) []int64 {
+	var data []int64
+
+	if g != nil {
+		for v := range g.edges[from] {
+			data = append(data, v)
+		}
+	}
+
+	sort.Slice(data, func(i, j int) bool {
+		return data[i] < data[j]
+	})
+
+	return data
+}
+
+// Check 校验变更并执行守卫, 状态不变时直接通过; 代码相同而字符串代码不同时无法区分, 拒绝变更
+func (g *ChoiceGraph) Check(from Choice, to Choice) error {
+	if g == nil || from.Code == to.Code && from.Key == to.Key {
+		return nil
+	}
+
+	if from.Code == to.Code {
+		return &ChoiceTransitionError{From: from, To: to, Err: ErrChoiceGraphCodes}
+	}
+
+	if !g.Can(from.Code, to.Code) {
+		return &ChoiceTransitionError{From: from, To: to}
+	}
+
+	for _, guard := range append(g.guards[:len(g.guards):len(g.guards)], g.edges[from.Code][to.Code]...) {
+		if err := guard(from, to); err != nil {
+			return &ChoiceTransitionError{From: from, To: to, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// ---------------------------------------------------------
+//
+//  GORM
+//
+// ---------------------------------------------------------
+
+// 支持状态变更校验的字段
+type choiceTransitioner interface {
+	// 从数据库读取的选项
+	choiceOrigin() (Choice, bool)
+	// 当前选项
+	choiceCurrent() Choice
+	// 根据代码或选项值查找目标选项
+	choiceResolve(value any) (Choice, error)
+	// 校验变更
+	choiceCheck(from Choice, to Choice) error
+}
+
+// 更新后将当前选项记为数据库中的选项
+type choiceCommitter interface {
+	choiceCommit()
+}
+
+// RegisterChoiceTransitions 注册GORM回调, 更新时校验ChoiceType及Enum字段的状态变更
+//
+// Enum[T]使用注册时的状态变更图; ChoiceType未设置Graph时使用enum标签指定的已注册枚举:
+//
+//	Status datatype.ChoiceType `gorm:"enum:order_status"`
+//
+// 变更前的状态取自查询时Scan的值, 未经查询的模型不做校验:
+//
+//	db.First(&order)
+//	order.Status.Update(OrderShipped)
+//	db.Save(&order) // 非法变更时返回*ChoiceTransitionError
+//
+//	db.Model(&order).Update("status", OrderShipped)
+func RegisterChoiceTransitions(db *gorm.DB) error {
+	if err := db.Callback().Update().Before("gorm:update").Register("datatype:choice_transition", choiceTransitionCheck); err != nil {
+		return err
+	}
+
+	return db.Callback().Update().After("gorm:update").Register("datatype:choice_transition_commit", choiceTransitionCommit)
+}
+
+func choiceTransitionCheck(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || !db.Statement.ReflectValue.IsValid() {
+		return
+	}
+
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+
+	if rv.Kind() != reflect.Struct {
+		return
+	}
+
+	updates, isMap := db.Statement.Dest.(map[string]any)
+
+	// Updates(struct)时ReflectValue为Model, 目标值取自Dest
+	dest := reflect.Indirect(reflect.ValueOf(db.Statement.Dest))
+	isStruct := !isMap && dest.Kind() == reflect.Struct && !(dest.CanAddr() && rv.CanAddr() && dest.Addr().Pointer() == rv.Addr().Pointer())
+
+	for _, field := range db.Statement.Schema.Fields {
+		value, _ := field.ValueOf(ctx, rv)
+
+		// 值为nil的指针字段没有可比较的状态
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Pointer && v.IsNil() {
+			continue
+		}
+
+		model, ok := value.(choiceTransitioner)
+
+		if !ok {
+			continue
+		}
+
+		model = choiceTransitionEnum(field, model)
+
+		from, loaded := model.choiceOrigin()
+
+		if !loaded {
+			continue
+		}
+
+		to := model.choiceCurrent()
+
+		if isMap {
+			v, ok := updates[field.DBName]
+
+			if !ok {
+				if v, ok = updates[field.Name]; !ok {
+					continue
+				}
+			}
+
+			var err error
+
+			if to, err = model.choiceResolve(v); err != nil {
+				db.AddError(err)
+
+				return
+			}
+		} else if isStruct {
+			v, ok := choiceTransitionDest(ctx, field, dest)
+
+			// 未更新的零值字段
+			if !ok {
+				continue
+			}
+
+			var err error
+
+			if to, err = model.choiceResolve(v); err != nil {
+				db.AddError(err)
+
+				return
+			}
+		}
+
+		if err := model.choiceCheck(from, to); err != nil {
+			db.AddError(err)
+
+			return
+		}
+	}
+}
+
+// choiceTransitionDest Updates(struct)中字段的值, 零值字段不会更新, 返回false
+func choiceTransitionDest(ctx context.Context, field *schema.Field, dest reflect.Value) (any, bool) {
+	if dest.Type() == field.Schema.ModelType {
+		v, zero := field.ValueOf(ctx, dest)
+
+		return v, !zero
+	}
+
+	fv := dest.FieldByName(field.Name)
+
+	if !fv.IsValid() || fv.IsZero() {
+		return nil, false
+	}
+
+	return fv.Interface(), true
+}
+
+// choiceTransitionEnum ChoiceType未设置Graph时使用enum标签指定的已注册枚举, Meta为空时一并使用枚举的选项
+func choiceTransitionEnum(field *schema.Field, model choiceTransitioner) choiceTransitioner {
+	var c ChoiceType
+
+	switch v := model.(type) {
+	case ChoiceType:
+		c = v
+	case *ChoiceType:
+		c = *v
+	default:
+		return model
+	}
+
+	if c.Graph != nil {
+		return model
+	}
+
+	name, ok := field.TagSettings["ENUM"]
+
+	if !ok {
+		return model
+	}
+
+	enum, ok := LookupChoiceEnum(name)
+
+	if !ok {
+		return model
+	}
+
+	c.Graph = enum.Graph
+
+	if len(c.Meta) == 0 {
+		c.Meta, c.Storage = enum.Choices, enum.Storage
+	}
+
+	return c
+}
+
+func choiceTransitionCommit(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Schema == nil || !db.Statement.ReflectValue.IsValid() {
+		return
+	}
+
+	ctx := db.Statement.Context
+	rv := db.Statement.ReflectValue
+
+	// Updates(map)时模型字段不会更新, 不做处理
+	if _, ok := db.Statement.Dest.(map[string]any); ok || rv.Kind() != reflect.Struct || !rv.CanAddr() {
+		return
+	}
+
+	for _, field := range db.Statement.Schema.Fields {
+		fv := field.ReflectValueOf(ctx, rv)
+
+		if fv.Kind() == reflect.Pointer {
+			if !fv.IsNil() {
+				if v, ok := fv.Interface().(choiceCommitter); ok {
+					v.choiceCommit()
+				}
+			}
+		} else if fv.CanAddr() {
+			if v, ok := fv.Addr().Interface().(choiceCommitter); ok {
+				v.choiceCommit()
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package datatype
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type choiceTransitionStatus struct{}
+
+var _ = RegisterEnum[choiceTransitionStatus](
+	"choice_transition_status",
+	Choice{Code: 1, Key: "PENDING", Value: "待支付"},
+	Choice{Code: 2, Key: "PAID", Value: "已支付"},
+	Choice{Code: 3, Key: "SHIPPED", Value: "已发货"},
+).WithGraph(NewChoiceGraph().Allow(1, 2).Allow(2, 3))
+
+type choiceTransitionOrder struct {
+	ID     int64
+	Status Enum[choiceTransitionStatus]
+	Source *ChoiceType `gorm:"enum:choice_transition_status"`
+}
+
+func choiceTransitionDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RegisterChoiceTransitions(db); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// choiceTransitionLoaded 模拟查询得到的模型
+func choiceTransitionLoaded(t *testing.T, code int64) choiceTransitionOrder {
+	t.Helper()
+
+	order := choiceTransitionOrder{ID: 1}
+
+	if err := order.Status.Scan(code); err != nil {
+		t.Fatal(err)
+	}
+
+	return order
+}
+
+func TestChoiceTransitionSave(t *testing.T) {
+	db := choiceTransitionDB(t)
+
+	order := choiceTransitionLoaded(t, 1)
+	order.Status.Update(3)
+
+	if err := db.Save(&order).Error; !errors.Is(err, ErrChoiceTransition) {
+		t.Errorf("Save 1 -> 3 got %v, want %v", err, ErrChoiceTransition)
+	}
+
+	order = choiceTransitionLoaded(t, 1)
+	order.Status.Update(2)
+
+	if err := db.Save(&order).Error; err != nil {
+		t.Errorf("Save 1 -> 2 got %v", err)
+	}
+}
+
+func TestChoiceTransitionUpdates(t *testing.T) {
+	db := choiceTransitionDB(t)
+
+	order := choiceTransitionLoaded(t, 1)
+
+	if err := db.Model(&order).Updates(map[string]any{"status": 3}).Error; !errors.Is(err, ErrChoiceTransition) {
+		t.Errorf("Updates(map) 1 -> 3 got %v, want %v", err, ErrChoiceTransition)
+	}
+
+	target, _ := NewEnum[choiceTransitionStatus](3)
+
+	if err := db.Model(&order).Updates(choiceTransitionOrder{Status: target}).Error; !errors.Is(err, ErrChoiceTransition) {
+		t.Errorf("Updates(struct) 1 -> 3 got %v, want %v", err, ErrChoiceTransition)
+	}
+
+	target, _ = NewEnum[choiceTransitionStatus](2)
+
+	if err := db.Model(&order).Updates(&choiceTransitionOrder{Status: target}).Error; err != nil {
+		t.Errorf("Updates(struct) 1 -> 2 got %v", err)
+	}
+}
+
+// ChoiceType通过enum标签使用已注册的状态变更图
+func TestChoiceTransitionTaggedChoiceType(t *testing.T) {
+	db := choiceTransitionDB(t)
+
+	order := choiceTransitionOrder{ID: 1, Source: &ChoiceType{}}
+
+	if err := order.Source.Scan("PENDING"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := order.Source.SetKey("SHIPPED"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Save(&order).Error; !errors.Is(err, ErrChoiceTransition) {
+		t.Errorf("Save PENDING -> SHIPPED got %v, want %v", err, ErrChoiceTransition)
+	}
+}
+
+// 没有Graph及Meta的ChoiceType不能放行任意变更
+func TestChoiceTypeTransitionWithoutGraph(t *testing.T) {
+	var c ChoiceType
+
+	if err := c.Scan(int64(1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Transition(3); !errors.Is(err, ErrChoiceGraphMissing) {
+		t.Errorf("got %v, want %v", err, ErrChoiceGraphMissing)
+	}
+
+	enum, _ := LookupEnum[choiceTransitionStatus]()
+	c = ChoiceType{Meta: enum.Choices, Graph: enum.Graph}
+	c.Set(1)
+
+	if err := c.Transition(3); !errors.Is(err, ErrChoiceTransition) {
+		t.Errorf("got %v, want %v", err, ErrChoiceTransition)
+	}
+
+	if err := c.Transition(2); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
@@ -0,0 +1,376 @@
+// choicegen 根据YAML/JSON枚举定义生成Choice常量、选项表及校验函数
+//
+//	//go:generate go run github.com/cnjacker/datatype/cmd/choicegen -spec enums.yaml -out enums_gen.go
+//
+// 定义格式:
+//
+//	package: model
+//	enums:
+//	  - name: OrderStatus
+//	    key: order_status
+//	    choices:
+//	      - name: Pending
+//	        code: 1
+//	        value: 待支付
+//	        labels:
+//	          en: Pending
+//	      - name: Closed
+//	        code: 9
+//	        value: 已关闭
+//	        deprecated: true
+//	    transitions:
+//	      Pending: [Closed]
+//	  - name: UserStatus
+//	    storage: key
+//	    choices:
+//	      - name: Active
+//	        code: 1
+//	        key: ACTIVE
+//	        value: 启用
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+type ChoiceSpec struct {
+	Name       string            `yaml:"name"`
+	Code       int64             `yaml:"code"`
+	Key        string            `yaml:"key"`
+	Value      string            `yaml:"value"`
+	Deprecated bool              `yaml:"deprecated"`
+	Labels     map[string]string `yaml:"labels"`
+}
+
+type EnumSpec struct {
+	Name    string `yaml:"name"`
+	Key     string `yaml:"key"`
+	Storage string `yaml:"storage"`
+	// 列类型, plain / enum / check
+	Column  string       `yaml:"column"`
+	Choices []ChoiceSpec `yaml:"choices"`
+	// 状态变更, 选项名称 -> 允许变更到的选项名称
+	Transitions map[string][]string `yaml:"transitions"`
+}
+
+// StoreKey 是否保存字符串代码, 原生枚举总是保存字符串代码
+func (e EnumSpec) StoreKey() bool {
+	return e.Storage == "key" || e.Column == "enum"
+}
+
+// ColumnAs 列类型常量
+func (e EnumSpec) ColumnAs() string {
+	switch e.Column {
+	case "enum":
+		return "datatype.ChoiceColumnEnum"
+	case "check":
+		return "datatype.ChoiceColumnCheck"
+	}
+
+	return ""
+}
+
+// Deprecated 已废弃的选项
+func (e EnumSpec) Deprecated() []ChoiceSpec {
+	var data []ChoiceSpec
+
+	for _, choice := range e.Choices {
+		if choice.Deprecated {
+			data = append(data, choice)
+		}
+	}
+
+	return data
+}
+
+// 枚举名称(key)允许的字符
+var specKey = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type Spec struct {
+	Package string     `yaml:"package"`
+	Enums   []EnumSpec `yaml:"enums"`
+}
+
+func main() {
+	specPath := flag.String("spec", "", "enum spec file (YAML or JSON)")
+	outPath := flag.String("out", "", "output Go file, defaults to stdout")
+	pkg := flag.String("package", "", "package name, defaults to spec package or $GOPACKAGE")
+
+	flag.Parse()
+
+	if *specPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	data, err := os.ReadFile(*specPath)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var spec Spec
+
+	// JSON是YAML的子集, 两种格式均可解析
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		log.Fatalf("parse %s: %v", *specPath, err)
+	}
+
+	if *pkg != "" {
+		spec.Package = *pkg
+	}
+
+	if spec.Package == "" {
+		spec.Package = os.Getenv("GOPACKAGE")
+	}
+
+	src, err := Generate(spec)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *outPath == "" {
+		os.Stdout.Write(src)
+
+		return
+	}
+
+	if err := os.WriteFile(*outPath, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// Generate 生成Go源码
+func Generate(spec Spec) ([]byte, error) {
+	if !token.IsIdentifier(spec.Package) {
+		return nil, fmt.Errorf("invalid package name %q", spec.Package)
+	}
+
+	// 生成的包级标识符 -> 来源, 用于检查重复声明
+	idents := map[string]string{}
+
+	declare := func(ident string, origin string) error {
+		if v, ok := idents[ident]; ok {
+			return fmt.Errorf("%s: generated identifier %s conflicts with %s", origin, ident, v)
+		}
+
+		idents[ident] = origin
+
+		return nil
+	}
+
+	for i, enum := range spec.Enums {
+		if !token.IsIdentifier(enum.Name) || !token.IsExported(enum.Name) {
+			return nil, fmt.Errorf("enum %q: name must be an exported Go identifier", enum.Name)
+		}
+
+		if enum.Key == "" {
+			spec.Enums[i].Key = snakeCase(enum.Name)
+		}
+
+		// 名称会写入生成代码的字符串字面量及fmt格式串
+		if !specKey.MatchString(spec.Enums[i].Key) {
+			return nil, fmt.Errorf("enum %s: key %q must contain only letters, digits, '_', '-' and '.'", enum.Name, spec.Enums[i].Key)
+		}
+
+		origin := "enum " + enum.Name
+		generated := []string{enum.Name + "Choices", enum.Name + "Enum", "New" + enum.Name, "New" + enum.Name + "Array", "Valid" + enum.Name, "Validate" + enum.Name}
+
+		if len(enum.Transitions) > 0 {
+			generated = append(generated, enum.Name+"Graph")
+		}
+
+		for _, ident := range generated {
+			if err := declare(ident, origin); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(enum.Choices) == 0 {
+			return nil, fmt.Errorf("enum %s: no choices", enum.Name)
+		}
+
+		if enum.Storage != "" && enum.Storage != "code" && enum.Storage != "key" {
+			return nil, fmt.Errorf("enum %s: storage must be code or key", enum.Name)
+		}
+
+		if enum.Column != "" && enum.Column != "plain" && enum.ColumnAs() == "" {
+			return nil, fmt.Errorf("enum %s: column must be plain, enum or check", enum.Name)
+		}
+
+		codes := map[int64]bool{}
+		keys := map[string]bool{}
+		names := map[string]bool{}
+
+		for _, choice := range enum.Choices {
+			if !token.IsIdentifier(enum.Name + choice.Name) {
+				return nil, fmt.Errorf("enum %s: invalid choice name %q", enum.Name, choice.Name)
+			}
+
+			if names[choice.Name] {
+				return nil, fmt.Errorf("enum %s: duplicate choice name %s", enum.Name, choice.Name)
+			}
+
+			if err := declare(enum.Name+choice.Name, fmt.Sprintf("enum %s choice %s", enum.Name, choice.Name)); err != nil {
+				return nil, err
+			}
+
+			if codes[choice.Code] {
+				return nil, fmt.Errorf("enum %s: duplicate code %d", enum.Name, choice.Code)
+			}
+
+			if enum.StoreKey() && choice.Key == "" {
+				return nil, fmt.Errorf("enum %s: choice %s requires a key", enum.Name, choice.Name)
+			}
+
+			if choice.Key != "" && keys[choice.Key] {
+				return nil, fmt.Errorf("enum %s: duplicate key %s", enum.Name, choice.Key)
+			}
+
+			codes[choice.Code] = true
+			keys[choice.Key] = true
+			names[choice.Name] = true
+		}
+
+		for from, to := range enum.Transitions {
+			for _, name := range append([]string{from}, to...) {
+				if !names[name] {
+					return nil, fmt.Errorf("enum %s: transition references unknown choice %s", enum.Name, name)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := sourceTemplate.Execute(&buf, spec); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// snakeCase OrderStatus -> order_status
+func snakeCase(s string) string {
+	var builder strings.Builder
+
+	for i, c := range s {
+		if unicode.IsUpper(c) {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+
+			c = unicode.ToLower(c)
+		}
+
+		builder.WriteRune(c)
+	}
+
+	return builder.String()
+}
+
+var sourceTemplate = template.Must(template.New("choice").Parse(`// Code generated by choicegen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"
+
+	"github.com/cnjacker/datatype"
+	"github.com/cnjacker/datatype/pqarray"
+)
+{{range $enum := .Enums}}
+// ---------------------------------------------------------
+//
+//  {{.Name}}
+//
+// ---------------------------------------------------------
+
+const (
+{{- range .Choices}}
+	{{$enum.Name}}{{.Name}} int64 = {{.Code}}
+{{- end}}
+)
+
+// {{.Name}}Choices 选项
+var {{.Name}}Choices = []datatype.Choice{
+{{- range .Choices}}
+	{Code: {{$enum.Name}}{{.Name}}{{if .Key}}, Key: {{printf "%q" .Key}}{{end}}, Value: {{printf "%q" .Value}}{{if .Deprecated}}, Deprecated: true{{end}}
+		{{- if .Labels}}, Labels: map[string]string{ {{- range $locale, $label := .Labels}}{{printf "%q" $locale}}: {{printf "%q" $label}}, {{end -}} }{{end}}},
+{{- end}}
+}
+
+// {{.Name}}Enum 注册表中的标记类型, 用于datatype.Enum[{{.Name}}Enum]
+type {{.Name}}Enum struct{}
+
+{{- if .Transitions}}
+
+// {{.Name}}Graph 状态变更图
+var {{.Name}}Graph = datatype.NewChoiceGraph()
+{{- range $from, $to := .Transitions}}.
+	Allow({{$enum.Name}}{{$from}}{{range $to}}, {{$enum.Name}}{{.}}{{end}})
+{{- end}}
+{{- end}}
+
+var _ = datatype.RegisterEnum[{{.Name}}Enum]({{printf "%q" .Key}}, {{.Name}}Choices...)
+{{- if .StoreKey}}.StoreAs(datatype.ChoiceStoreKey){{end}}
+{{- if .ColumnAs}}.ColumnAs({{.ColumnAs}}){{end}}
+{{- if .Transitions}}.WithGraph({{.Name}}Graph){{end}}
+
+// New{{.Name}}
+func New{{.Name}}(code int64) datatype.ChoiceType {
+	c := datatype.ChoiceType{Meta: {{.Name}}Choices{{if .StoreKey}}, Storage: datatype.ChoiceStoreKey{{end}}{{if .Transitions}}, Graph: {{.Name}}Graph{{end}}}
+	c.Update(code)
+
+	return c
+}
+
+// New{{.Name}}Array
+func New{{.Name}}Array(codes ...int64) pqarray.ChoiceArray {
+	a := pqarray.ChoiceArray{Meta: {{.Name}}Choices{{if .StoreKey}}, Storage: datatype.ChoiceStoreKey{{end}}}
+
+	for _, code := range codes {
+		a.Choices = append(a.Choices, New{{.Name}}(code))
+	}
+
+	return a
+}
+
+// Valid{{.Name}} 代码是否有效
+func Valid{{.Name}}(code int64) bool {
+	switch code {
+	case {{range $i, $c := .Choices}}{{if $i}}, {{end}}{{$enum.Name}}{{$c.Name}}{{end}}:
+		return true
+	}
+
+	return false
+}
+
+// Validate{{.Name}} 代码无效或已废弃时返回错误
+func Validate{{.Name}}(code int64) error {
+{{- if .Deprecated}}
+	switch code {
+	case {{range $i, $c := .Deprecated}}{{if $i}}, {{end}}{{$enum.Name}}{{$c.Name}}{{end}}:
+		return fmt.Errorf("{{$enum.Key}}: code %d is deprecated", code)
+	}
+{{end}}
+	if !Valid{{.Name}}(code) {
+		return fmt.Errorf("%w: {{$enum.Key}} %d", datatype.ErrChoiceUnknown, code)
+	}
+
+	return nil
+}
+{{end}}`))
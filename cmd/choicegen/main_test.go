@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGenerateGolden(t *testing.T) {
+	data, err := os.ReadFile("testdata/enums.yaml")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spec Spec
+
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Generate(spec)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile("testdata/enums.golden", got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile("testdata/enums.golden")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Errorf("Generate output differs from testdata/enums.golden, run go test -update\n%s", got)
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	choice := func(name string, code int64) ChoiceSpec {
+		return ChoiceSpec{Name: name, Code: code, Value: name}
+	}
+
+	cases := map[string]struct {
+		enums []EnumSpec
+		err   string
+	}{
+		"choice named Enum": {
+			[]EnumSpec{{Name: "Status", Choices: []ChoiceSpec{choice("Enum", 1)}}},
+			"conflicts",
+		},
+		"choice named Choices": {
+			[]EnumSpec{{Name: "Status", Choices: []ChoiceSpec{choice("Choices", 1)}}},
+			"conflicts",
+		},
+		"choice named Graph": {
+			[]EnumSpec{{Name: "Status", Choices: []ChoiceSpec{choice("Graph", 1), choice("Open", 2)}, Transitions: map[string][]string{"Open": {"Graph"}}}},
+			"conflicts",
+		},
+		"choice across enums": {
+			[]EnumSpec{
+				{Name: "Order", Choices: []ChoiceSpec{choice("StatusPending", 1)}},
+				{Name: "OrderStatus", Choices: []ChoiceSpec{choice("Pending", 1)}},
+			},
+			"conflicts",
+		},
+		"quote in key": {
+			[]EnumSpec{{Name: "Status", Key: `status"`, Choices: []ChoiceSpec{choice("Open", 1)}}},
+			"key",
+		},
+		"percent in key": {
+			[]EnumSpec{{Name: "Status", Key: "status%d", Choices: []ChoiceSpec{choice("Open", 1)}}},
+			"key",
+		},
+	}
+
+	for name, c := range cases {
+		_, err := Generate(Spec{Package: "model", Enums: c.enums})
+
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: got %v, want error containing %q", name, err, c.err)
+		}
+	}
+}
@@ -1,20 +1,41 @@
 package datatype
 
 import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
 	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
 	"strings"
 )
 
+var (
+	ErrEncryptCorrupted = errors.New("datatype: encrypted value is corrupted")
+	ErrEncryptKey       = errors.New("datatype: encryption key not found")
+	ErrEncryptBytes     = errors.New("datatype: encrypt service does not support binary data")
+	ErrEncryptService   = errors.New("datatype: encrypt service not configured")
+)
+
 type EncryptService interface {
 	Encode(value string) string
 	Decode(value string) string
 	Mask(value string) string
 }
 
-// 默认加密服务
+// 支持错误返回的加密服务
+type EncryptServiceWithError interface {
+	EncryptService
+	TryEncode(value string) (string, error)
+	TryDecode(value string) (string, error)
+}
+
+// 默认加密服务, 只混淆数字且不做完整性校验, 需要检测篡改时使用AESEncryptService
 type DefaultEncryptService struct{}
 
 func (es DefaultEncryptService) Encode(value string) string {
@@ -111,6 +132,16 @@ func (es DefaultEncryptService) Decode(value string) string {
 	return strings.Join(data, "")
 }
 
+// TryEncode
+func (es DefaultEncryptService) TryEncode(value string) (string, error) {
+	return es.Encode(value), nil
+}
+
+// TryDecode 与Decode一致, 不校验密文
+func (es DefaultEncryptService) TryDecode(value string) (string, error) {
+	return es.Decode(value), nil
+}
+
 func (es DefaultEncryptService) Mask(value string) string {
 	var data []string
 
@@ -131,34 +162,374 @@ func (es DefaultEncryptService) Mask(value string) string {
 	return strings.Join(data, "")
 }
 
+// 支持二进制数据的加密服务, 应为认证加密(如AES-GCM), 密文不泄露任何明文内容
+type BytesEncryptService interface {
+	EncodeBytes(value []byte) (string, error)
+	DecodeBytes(value string) ([]byte, error)
+}
+
+// AES-GCM加密服务, 密文带认证标签, 篡改后无法解密
+type AESEncryptService struct {
+	// 当前密钥ID
+	KeyID string
+	// 密钥(16/24/32字节), 保留旧密钥用于轮换
+	Keys map[string][]byte
+}
+
+func (es AESEncryptService) Encode(value string) string {
+	v, _ := es.TryEncode(value)
+
+	return v
+}
+
+func (es AESEncryptService) Decode(value string) string {
+	v, _ := es.TryDecode(value)
+
+	return v
+}
+
+func (es AESEncryptService) Mask(value string) string {
+	return DefaultEncryptService{}.Mask(value)
+}
+
+// TryEncode 密文格式: $aes$<KeyID>$<base64(nonce+ciphertext)>
+func (es AESEncryptService) TryEncode(value string) (string, error) {
+	data, err := es.seal(es.KeyID, []byte(value), nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return "$aes$" + es.KeyID + "$" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (es AESEncryptService) TryDecode(value string) (string, error) {
+	v, err := es.open(value, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(v), nil
+}
+
+func (es AESEncryptService) EncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	data, err := es.seal(es.KeyID, value, ec.AssociatedData())
+
+	if err != nil {
+		return "", err
+	}
+
+	return "$aes$" + es.KeyID + "$" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (es AESEncryptService) DecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error) {
+	return es.open(value, ec.AssociatedData())
+}
+
+func (es AESEncryptService) EncodeBytes(value []byte) (string, error) {
+	return es.TryEncode(string(value))
+}
+
+func (es AESEncryptService) DecodeBytes(value string) ([]byte, error) {
+	return es.open(value, nil)
+}
+
+// Rotate 使用当前密钥重新加密
+func (es AESEncryptService) Rotate(value string) (string, error) {
+	v, err := es.TryDecode(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return es.TryEncode(v)
+}
+
+// NeedsRotation 密文是否使用了非当前密钥
+func (es AESEncryptService) NeedsRotation(value string) bool {
+	keyID, _, err := splitAESCiphertext(value)
+
+	return err != nil || keyID != es.KeyID
+}
+
+func (es AESEncryptService) seal(keyID string, plaintext []byte, ad []byte) ([]byte, error) {
+	key, ok := es.Keys[keyID]
+
+	if !ok {
+		return nil, ErrEncryptKey
+	}
+
+	return aesSeal(key, plaintext, ad)
+}
+
+func (es AESEncryptService) open(value string, ad []byte) ([]byte, error) {
+	keyID, data, err := splitAESCiphertext(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := es.Keys[keyID]
+
+	if !ok {
+		return nil, ErrEncryptKey
+	}
+
+	return aesOpen(key, data, ad)
+}
+
+// aesSeal AES-GCM加密, 输出nonce+ciphertext
+func aesSeal(key []byte, plaintext []byte, ad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// aesOpen AES-GCM解密, 认证失败视为密文被篡改
+func aesOpen(key []byte, data []byte, ad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrEncryptCorrupted
+	}
+
+	v, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], ad)
+
+	if err != nil {
+		return nil, ErrEncryptCorrupted
+	}
+
+	return v, nil
+}
+
+// splitAESCiphertext
+func splitAESCiphertext(value string) (string, []byte, error) {
+	parts := strings.Split(value, "$")
+
+	if len(parts) != 4 || parts[0] != "" || parts[1] != "aes" {
+		return "", nil, ErrEncryptCorrupted
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[3])
+
+	if err != nil {
+		return "", nil, ErrEncryptCorrupted
+	}
+
+	return parts[2], data, nil
+}
+
+// JSON输出策略
+type EncryptJSONPolicy uint8
+
+const (
+	// 掩码, EncryptedJSON及EncryptedBytes的内容完全隐藏
+	EncryptJSONMask EncryptJSONPolicy = iota
+	// 明文
+	EncryptJSONPlaintext
+	// 密文
+	EncryptJSONCiphertext
+)
+
+type encryptJSONPolicyKey struct{}
+
+// WithEncryptJSONPolicy 设置上下文中的JSON输出策略
+func WithEncryptJSONPolicy(ctx context.Context, policy EncryptJSONPolicy) context.Context {
+	return context.WithValue(ctx, encryptJSONPolicyKey{}, policy)
+}
+
+// EncryptJSONPolicyFrom 获取上下文中的JSON输出策略, 未设置时使用EncryptOptions.JSONPolicy
+func EncryptJSONPolicyFrom(ctx context.Context) EncryptJSONPolicy {
+	if ctx != nil {
+		if v, ok := ctx.Value(encryptJSONPolicyKey{}).(EncryptJSONPolicy); ok {
+			return v
+		}
+	}
+
+	return EncryptOptions.JSONPolicy
+}
+
 type EncryptConfig struct {
 	// 加密服务
 	Service EncryptService
+	// 默认JSON输出策略
+	JSONPolicy EncryptJSONPolicy
 }
 
 var EncryptOptions = EncryptConfig{
-	Service: DefaultEncryptService{},
+	Service:    DefaultEncryptService{},
+	JSONPolicy: EncryptJSONMask,
+}
+
+// EncryptEncode 使用当前加密服务加密
+func EncryptEncode(value string) (string, error) {
+	return encryptServiceEncode(EncryptOptions.Service, value)
+}
+
+// EncryptDecode 使用当前加密服务解密
+func EncryptDecode(value string) (string, error) {
+	return encryptServiceDecode(EncryptOptions.Service, value)
+}
+
+// encryptServiceEncode 未配置加密服务时返回ErrEncryptService, 不以明文保存
+func encryptServiceEncode(service EncryptService, value string) (string, error) {
+	if service == nil {
+		return "", ErrEncryptService
+	}
+
+	if s, ok := service.(EncryptServiceWithError); ok {
+		return s.TryEncode(value)
+	}
+
+	return service.Encode(value), nil
+}
+
+// encryptServiceDecode
+func encryptServiceDecode(service EncryptService, value string) (string, error) {
+	if service == nil {
+		return "", ErrEncryptService
+	}
+
+	if s, ok := service.(EncryptServiceWithError); ok {
+		return s.TryDecode(value)
+	}
+
+	return service.Decode(value), nil
+}
+
+// EncryptEncodeBytes 加密二进制数据, 加密服务须实现BytesEncryptService, 否则返回ErrEncryptBytes
+//
+// DefaultEncryptService只替换数字, 不能用于十六进制、JSON等文本
+func EncryptEncodeBytes(value []byte) (string, error) {
+	if s, ok := EncryptOptions.Service.(BytesEncryptService); ok {
+		return s.EncodeBytes(value)
+	}
+
+	return "", ErrEncryptBytes
+}
+
+// EncryptDecodeBytes 解密二进制数据
+func EncryptDecodeBytes(value string) ([]byte, error) {
+	if s, ok := EncryptOptions.Service.(BytesEncryptService); ok {
+		return s.DecodeBytes(value)
+	}
+
+	return nil, ErrEncryptBytes
 }
 
 type Encrypt string
 
 // GORM
 func (e *Encrypt) Scan(value any) error {
-	if v, ok := value.(string); ok {
-		*e = Encrypt(EncryptOptions.Service.Decode(v))
+	var s string
+
+	switch v := value.(type) {
+	case nil:
+		*e = ""
+
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into Encrypt", value)
 	}
 
+	v, err := EncryptDecode(s)
+
+	if err != nil {
+		return err
+	}
+
+	*e = Encrypt(v)
+
 	return nil
 }
 
 func (e Encrypt) Value() (driver.Value, error) {
-	return EncryptOptions.Service.Encode(string(e)), nil
+	return EncryptEncode(string(e))
 }
 
+// Mask 未配置加密服务时完全隐藏
 func (e Encrypt) Mask() string {
+	if EncryptOptions.Service == nil {
+		if e == "" {
+			return ""
+		}
+
+		return encryptedRedacted
+	}
+
 	return EncryptOptions.Service.Mask(string(e))
 }
 
+// JSON
+func (e Encrypt) MarshalJSON() ([]byte, error) {
+	return e.MarshalJSONContext(context.Background())
+}
+
+func (e Encrypt) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	switch EncryptJSONPolicyFrom(ctx) {
+	case EncryptJSONPlaintext:
+		return json.Marshal(string(e))
+	case EncryptJSONCiphertext:
+		v, err := EncryptEncode(string(e))
+
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(v)
+	default:
+		return json.Marshal(e.Mask())
+	}
+}
+
+// UnmarshalJSON 输入为明文; 与当前值的掩码相同时保留当前值, 避免回传的掩码覆盖原值
+func (e *Encrypt) UnmarshalJSON(data []byte) error {
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if *e != "" && s == e.Mask() {
+		return nil
+	}
+
+	*e = Encrypt(s)
+
+	return nil
+}
+
 // String
 func (e Encrypt) String() string {
 	return e.Mask()
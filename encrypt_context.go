@@ -0,0 +1,249 @@
+package datatype
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrEncryptRowID   = errors.New("datatype: row bound encryption requires a primary key value")
+	ErrEncryptSubject = errors.New("datatype: subject bound encryption requires a subject value")
+	ErrEncryptContext = errors.New("datatype: encrypt service does not support associated data")
+)
+
+// 加密上下文, 作为关联数据与密文绑定, 密文无法在字段或行之间互换
+type EncryptContext struct {
+	// 表名
+	Table string
+	// 列名
+	Column string
+	// 行主键
+	RowID string
+	// 数据主体(如用户ID), 用于按主体加密, 不参与关联数据
+	Subject string
+}
+
+// AssociatedData
+func (ec EncryptContext) AssociatedData() []byte {
+	var builder strings.Builder
+
+	for _, v := range []string{ec.Table, ec.Column, ec.RowID} {
+		builder.WriteString(fmt.Sprintf("%d:%s;", len(v), v))
+	}
+
+	return []byte(builder.String())
+}
+
+// 支持关联数据的加密服务, ctx为发起读写的请求上下文(如GORM语句的Context), 可用于审计
+type ContextEncryptService interface {
+	EncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error)
+	DecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error)
+}
+
+// EncryptEncodeContext 使用当前加密服务加密并绑定关联数据, 加密服务须实现ContextEncryptService
+func EncryptEncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	return encryptServiceEncodeContext(ctx, EncryptOptions.Service, ec, value)
+}
+
+// EncryptDecodeContext 使用当前加密服务解密并校验关联数据
+func EncryptDecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error) {
+	return encryptServiceDecodeContext(ctx, EncryptOptions.Service, ec, value)
+}
+
+// encryptServiceEncodeContext 不支持关联数据的加密服务返回ErrEncryptContext, 不退化为普通加密
+func encryptServiceEncodeContext(ctx context.Context, service EncryptService, ec EncryptContext, value []byte) (string, error) {
+	if service == nil {
+		return "", ErrEncryptService
+	}
+
+	if s, ok := service.(ContextEncryptService); ok {
+		return s.EncodeContext(ctx, ec, value)
+	}
+
+	return "", ErrEncryptContext
+}
+
+// encryptServiceDecodeContext
+func encryptServiceDecodeContext(ctx context.Context, service EncryptService, ec EncryptContext, value string) ([]byte, error) {
+	if service == nil {
+		return nil, ErrEncryptService
+	}
+
+	if s, ok := service.(ContextEncryptService); ok {
+		return s.DecodeContext(ctx, ec, value)
+	}
+
+	return nil, ErrEncryptContext
+}
+
+// ---------------------------------------------------------
+//
+//  EncryptSerializer
+//
+// ---------------------------------------------------------
+
+func init() {
+	schema.RegisterSerializer("encrypt", EncryptSerializer{})
+	schema.RegisterSerializer("encrypt_row", EncryptSerializer{BindRowID: true})
+	schema.RegisterSerializer("encrypt_subject", EncryptSerializer{BindSubject: true})
+}
+
+// GORM序列化器, 以表名、列名(及主键)作为关联数据加密字段
+//
+//	Phone   datatype.Encrypt       `gorm:"serializer:encrypt"`
+//	IDCard  datatype.Encrypt       `gorm:"serializer:encrypt_row"`
+//	Profile datatype.EncryptedJSON `gorm:"serializer:encrypt"`
+//
+// 加密服务须实现ContextEncryptService(如AESEncryptService), 否则返回ErrEncryptContext
+//
+// 绑定主键时, 主键必须在创建前赋值, 且查询时需要在该字段之前选择主键
+//
+// 绑定数据主体时, 主体字段由subject标签指定, 默认为主键, 配合ShreddingEncryptService使用
+//
+//	Phone datatype.Encrypt `gorm:"serializer:encrypt_subject;subject:UserID"`
+type EncryptSerializer struct {
+	// 是否绑定主键
+	BindRowID bool
+	// 是否绑定数据主体
+	BindSubject bool
+}
+
+func (es EncryptSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue any) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var s string
+
+		switch v := dbValue.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return fmt.Errorf("datatype: cannot scan %T into %s", dbValue, field.Name)
+		}
+
+		if s != "" {
+			ec, err := es.Context(ctx, field, dst)
+
+			if err != nil {
+				return err
+			}
+
+			kind := field.FieldType.Kind()
+			data, err := EncryptDecodeContext(ctx, ec, s)
+
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case kind == reflect.String:
+				fieldValue.Elem().SetString(string(data))
+			case kind == reflect.Slice && field.FieldType.Elem().Kind() == reflect.Uint8:
+				fieldValue.Elem().SetBytes(data)
+			default:
+				if err := json.Unmarshal(data, fieldValue.Interface()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+
+	return nil
+}
+
+func (es EncryptSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue any) (any, error) {
+	rv := reflect.ValueOf(fieldValue)
+
+	var data []byte
+
+	switch {
+	case !rv.IsValid():
+		return nil, nil
+	case rv.Kind() == reflect.String:
+		data = []byte(rv.String())
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		if rv.IsNil() {
+			return nil, nil
+		}
+
+		data = rv.Bytes()
+	default:
+		if (rv.Kind() == reflect.Map || rv.Kind() == reflect.Pointer) && rv.IsNil() {
+			return nil, nil
+		}
+
+		// EncryptedJSON的MarshalJSON输出掩码, 按普通map序列化
+		if v, ok := fieldValue.(EncryptedJSON); ok {
+			fieldValue = map[string]any(v)
+		}
+
+		v, err := json.Marshal(fieldValue)
+
+		if err != nil {
+			return nil, err
+		}
+
+		data = v
+	}
+
+	ec, err := es.Context(ctx, field, dst)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return EncryptEncodeContext(ctx, ec, data)
+}
+
+// Context 根据字段及所在行生成加密上下文
+func (es EncryptSerializer) Context(ctx context.Context, field *schema.Field, dst reflect.Value) (EncryptContext, error) {
+	ec := EncryptContext{Table: field.Schema.Table, Column: field.DBName}
+
+	if es.BindRowID {
+		pk := field.Schema.PrioritizedPrimaryField
+
+		if pk == nil {
+			return ec, ErrEncryptRowID
+		}
+
+		v, zero := pk.ValueOf(ctx, dst)
+
+		if zero {
+			return ec, ErrEncryptRowID
+		}
+
+		ec.RowID = fmt.Sprint(v)
+	}
+
+	if es.BindSubject {
+		subject := field.Schema.PrioritizedPrimaryField
+
+		if name, ok := field.TagSettings["SUBJECT"]; ok {
+			subject = field.Schema.LookUpField(name)
+		}
+
+		if subject == nil {
+			return ec, ErrEncryptSubject
+		}
+
+		v, zero := subject.ValueOf(ctx, dst)
+
+		if zero {
+			return ec, ErrEncryptSubject
+		}
+
+		ec.Subject = fmt.Sprint(v)
+	}
+
+	return ec, nil
+}
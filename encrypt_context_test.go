@@ -0,0 +1,87 @@
+package datatype
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type encryptContextUser struct {
+	ID     int64
+	Phone  Encrypt `gorm:"serializer:encrypt_row"`
+	IDCard Encrypt `gorm:"serializer:encrypt_row"`
+}
+
+func encryptContextService(t *testing.T, service EncryptService) {
+	t.Helper()
+
+	origin := EncryptOptions.Service
+	EncryptOptions.Service = service
+
+	t.Cleanup(func() {
+		EncryptOptions.Service = origin
+	})
+}
+
+// 密文在列或行之间互换后无法解密
+func TestEncryptSerializerBinding(t *testing.T) {
+	encryptContextService(t, AESEncryptService{KeyID: "1", Keys: map[string][]byte{"1": make([]byte, 32)}})
+
+	s, err := schema.Parse(&encryptContextUser{}, &sync.Map{}, schema.NamingStrategy{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	serializer := EncryptSerializer{BindRowID: true}
+	phone, idCard := s.LookUpField("Phone"), s.LookUpField("IDCard")
+
+	user := encryptContextUser{ID: 1, Phone: "13800138000"}
+	data, err := serializer.Value(ctx, phone, reflect.ValueOf(&user).Elem(), user.Phone)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded encryptContextUser
+
+	loaded.ID = 1
+
+	if err := serializer.Scan(ctx, phone, reflect.ValueOf(&loaded).Elem(), data); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Phone != user.Phone {
+		t.Fatalf("got %q, want %q", loaded.Phone, user.Phone)
+	}
+
+	if err := serializer.Scan(ctx, idCard, reflect.ValueOf(&loaded).Elem(), data); err == nil {
+		t.Error("ciphertext swapped between columns decrypted")
+	}
+
+	other := encryptContextUser{ID: 2}
+
+	if err := serializer.Scan(ctx, phone, reflect.ValueOf(&other).Elem(), data); err == nil {
+		t.Error("ciphertext swapped between rows decrypted")
+	}
+}
+
+// 不支持关联数据的加密服务不能退化为普通加密
+func TestEncryptContextUnsupported(t *testing.T) {
+	encryptContextService(t, DefaultEncryptService{})
+
+	ec := EncryptContext{Table: "users", Column: "phone", RowID: "1"}
+
+	if _, err := EncryptEncodeContext(context.Background(), ec, []byte("13800138000")); !errors.Is(err, ErrEncryptContext) {
+		t.Errorf("encode got %v, want %v", err, ErrEncryptContext)
+	}
+
+	if _, err := EncryptDecodeContext(context.Background(), ec, "13800138000"); !errors.Is(err, ErrEncryptContext) {
+		t.Errorf("decode got %v, want %v", err, ErrEncryptContext)
+	}
+}
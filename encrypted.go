@@ -0,0 +1,245 @@
+package datatype
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ---------------------------------------------------------
+//
+//  EncryptedJSON
+//
+// ---------------------------------------------------------
+
+// 加密的JSON对象, 加密服务须实现BytesEncryptService
+type EncryptedJSON map[string]any
+
+// GORM
+func (j EncryptedJSON) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlserver" {
+		return "NVARCHAR(MAX)"
+	} else {
+		return "TEXT"
+	}
+}
+
+func (j EncryptedJSON) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if len(j) > 0 {
+		v, err := json.Marshal(map[string]any(j))
+
+		if err == nil {
+			var data string
+
+			if data, err = EncryptEncodeBytes(v); err == nil {
+				return gorm.Expr("?", data)
+			}
+		}
+
+		db.AddError(err)
+	}
+
+	return gorm.Expr("NULL")
+}
+
+func (j *EncryptedJSON) Scan(value any) error {
+	if value != nil {
+		var s string
+
+		switch v := value.(type) {
+		case []byte:
+			s = string(v)
+		case string:
+			s = v
+		default:
+			return fmt.Errorf("datatype: cannot scan %T into EncryptedJSON", value)
+		}
+
+		if s != "" {
+			bytes, err := EncryptDecodeBytes(s)
+
+			if err != nil {
+				return err
+			}
+
+			return json.Unmarshal(bytes, (*map[string]any)(j))
+		}
+	}
+
+	*j = nil
+
+	return nil
+}
+
+// JSON
+func (j EncryptedJSON) MarshalJSON() ([]byte, error) {
+	return j.MarshalJSONContext(context.Background())
+}
+
+func (j EncryptedJSON) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	if j == nil {
+		return []byte("null"), nil
+	}
+
+	switch EncryptJSONPolicyFrom(ctx) {
+	case EncryptJSONPlaintext:
+		return json.Marshal(map[string]any(j))
+	case EncryptJSONCiphertext:
+		v, err := json.Marshal(map[string]any(j))
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := EncryptEncodeBytes(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(data)
+	default:
+		return json.Marshal(encryptedJSONMask(map[string]any(j)))
+	}
+}
+
+func (j *EncryptedJSON) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*map[string]any)(j))
+}
+
+// String
+func (j EncryptedJSON) String() string {
+	if v, err := json.MarshalIndent(encryptedJSONMask(map[string]any(j)), "", "    "); err == nil {
+		return string(v)
+	}
+
+	return ""
+}
+
+// 隐藏内容的占位符
+const encryptedRedacted = "********"
+
+// encryptedJSONMask 保留对象结构, 所有非空的值替换为占位符
+func encryptedJSONMask(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		data := make(map[string]any, len(v))
+
+		for key, val := range v {
+			data[key] = encryptedJSONMask(val)
+		}
+
+		return data
+	case []any:
+		data := make([]any, len(v))
+
+		for i, val := range v {
+			data[i] = encryptedJSONMask(val)
+		}
+
+		return data
+	case nil:
+		return nil
+	default:
+		return encryptedRedacted
+	}
+}
+
+// ---------------------------------------------------------
+//
+//  EncryptedBytes
+//
+// ---------------------------------------------------------
+
+// 加密的二进制数据, 加密服务须实现BytesEncryptService
+type EncryptedBytes []byte
+
+// GORM
+func (b EncryptedBytes) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlserver" {
+		return "NVARCHAR(MAX)"
+	} else {
+		return "TEXT"
+	}
+}
+
+func (b *EncryptedBytes) Scan(value any) error {
+	var s string
+
+	switch v := value.(type) {
+	case nil:
+		*b = nil
+
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into EncryptedBytes", value)
+	}
+
+	if s == "" {
+		*b = nil
+
+		return nil
+	}
+
+	v, err := EncryptDecodeBytes(s)
+
+	if err != nil {
+		return err
+	}
+
+	*b = EncryptedBytes(v)
+
+	return nil
+}
+
+func (b EncryptedBytes) Value() (driver.Value, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	return EncryptEncodeBytes(b)
+}
+
+// JSON
+func (b EncryptedBytes) MarshalJSON() ([]byte, error) {
+	return b.MarshalJSONContext(context.Background())
+}
+
+func (b EncryptedBytes) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+
+	switch EncryptJSONPolicyFrom(ctx) {
+	case EncryptJSONPlaintext:
+		return json.Marshal([]byte(b))
+	case EncryptJSONCiphertext:
+		v, err := EncryptEncodeBytes(b)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(v)
+	default:
+		return json.Marshal(encryptedRedacted)
+	}
+}
+
+func (b *EncryptedBytes) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, (*[]byte)(b))
+}
+
+// String
+func (b EncryptedBytes) String() string {
+	return encryptedRedacted
+}
@@ -0,0 +1,337 @@
+package datatype
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrChoiceUnknown      = errors.New("datatype: unknown choice code")
+	ErrEnumNotRegistered  = errors.New("datatype: enum not registered")
+	ErrEnumNameRegistered = errors.New("datatype: enum name already registered")
+)
+
+// 枚举定义
+type ChoiceEnum struct {
+	// 名称
+	Name string
+	// 选项
+	Choices []Choice
+	// 存储方式
+	Storage ChoiceStorage
+	// 列的数据库类型
+	Column ChoiceColumn
+	// 状态变更图
+	Graph *ChoiceGraph
+}
+
+// Lookup 根据代码查找选项
+func (e *ChoiceEnum) Lookup(code int64) (Choice, bool) {
+	return Choice{Code: code}.lookup(e.Choices, false)
+}
+
+// LookupKey 根据字符串代码查找选项
+func (e *ChoiceEnum) LookupKey(key string) (Choice, bool) {
+	return Choice{Key: key}.lookup(e.Choices, true)
+}
+
+// StoreAs 设置存储方式, 应在注册时调用
+//
+//	var _ = datatype.RegisterEnum[UserStatus]("user_status", choices...).StoreAs(datatype.ChoiceStoreKey)
+func (e *ChoiceEnum) StoreAs(storage ChoiceStorage) *ChoiceEnum {
+	e.Storage = storage
+
+	return e
+}
+
+// WithGraph 设置状态变更图, 应在注册时调用; 状态变更图以代码为节点, 选项代码重复(如仅有字符串代码)时panic
+func (e *ChoiceEnum) WithGraph(graph *ChoiceGraph) *ChoiceEnum {
+	codes := map[int64]bool{}
+
+	for _, choice := range e.Choices {
+		if codes[choice.Code] {
+			panic(fmt.Errorf("%w: %s", ErrChoiceGraphCodes, e.Name))
+		}
+
+		codes[choice.Code] = true
+	}
+
+	e.Graph = graph
+
+	return e
+}
+
+var choiceRegistry = struct {
+	sync.RWMutex
+	types map[reflect.Type]*ChoiceEnum
+	names map[string]*ChoiceEnum
+}{
+	types: map[reflect.Type]*ChoiceEnum{},
+	names: map[string]*ChoiceEnum{},
+}
+
+// RegisterEnum 注册枚举, T为区分枚举的标记类型
+//
+//	type OrderStatus struct{}
+//
+//	var _ = datatype.RegisterEnum[OrderStatus]("order_status", datatype.Choice{Code: 1, Value: "待支付"})
+//
+//	type Order struct {
+//		Status datatype.Enum[OrderStatus]
+//	}
+func RegisterEnum[T any](name string, choices ...Choice) *ChoiceEnum {
+	enum := &ChoiceEnum{Name: name, Choices: choices}
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	choiceRegistry.Lock()
+	defer choiceRegistry.Unlock()
+
+	if v, ok := choiceRegistry.names[name]; ok && v != choiceRegistry.types[t] {
+		panic(fmt.Errorf("%w: %s", ErrEnumNameRegistered, name))
+	}
+
+	choiceRegistry.types[t] = enum
+	choiceRegistry.names[name] = enum
+
+	return enum
+}
+
+// LookupEnum 根据标记类型查找枚举
+func LookupEnum[T any]() (*ChoiceEnum, bool) {
+	choiceRegistry.RLock()
+	defer choiceRegistry.RUnlock()
+
+	enum, ok := choiceRegistry.types[reflect.TypeOf((*T)(nil)).Elem()]
+
+	return enum, ok
+}
+
+// LookupChoiceEnum 根据名称查找枚举
+func LookupChoiceEnum(name string) (*ChoiceEnum, bool) {
+	choiceRegistry.RLock()
+	defer choiceRegistry.RUnlock()
+
+	enum, ok := choiceRegistry.names[name]
+
+	return enum, ok
+}
+
+// ChoiceEnums 所有已注册的枚举, 按名称排序
+func ChoiceEnums() []*ChoiceEnum {
+	choiceRegistry.RLock()
+	defer choiceRegistry.RUnlock()
+
+	var data []*ChoiceEnum
+
+	for _, enum := range choiceRegistry.names {
+		data = append(data, enum)
+	}
+
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].Name < data[j].Name
+	})
+
+	return data
+}
+
+// ---------------------------------------------------------
+//
+//  Enum
+//
+// ---------------------------------------------------------
+
+// 基于注册表的枚举, 选项由RegisterEnum[T]注册, 零值即可Scan及反序列化
+type Enum[T any] struct {
+	Choice Choice `json:"choice" validate:"required"`
+
+	// 从数据库读取的选项, 用于校验状态变更
+	origin Choice
+	loaded bool
+}
+
+// NewEnum
+func NewEnum[T any](code int64) (Enum[T], error) {
+	var e Enum[T]
+
+	return e, e.Update(code)
+}
+
+// Meta 枚举定义
+func (e Enum[T]) Meta() (*ChoiceEnum, error) {
+	return enumMeta[T]()
+}
+
+// enumMeta 查找T注册的枚举, 未注册时返回ErrEnumNotRegistered
+func enumMeta[T any]() (*ChoiceEnum, error) {
+	enum, ok := LookupEnum[T]()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEnumNotRegistered, reflect.TypeOf((*T)(nil)).Elem())
+	}
+
+	return enum, nil
+}
+
+// Update
+func (e *Enum[T]) Update(code int64) error {
+	return e.set(Choice{Code: code}, false)
+}
+
+// UpdateKey 根据字符串代码设置
+func (e *Enum[T]) UpdateKey(key string) error {
+	return e.set(Choice{Key: key}, true)
+}
+
+// Transition 按枚举的状态变更图变更为to
+func (e *Enum[T]) Transition(to int64) error {
+	choice, err := e.choiceResolve(to)
+
+	if err != nil {
+		return err
+	}
+
+	if err := e.choiceCheck(e.Choice, choice); err != nil {
+		return err
+	}
+
+	e.Choice = choice
+
+	return nil
+}
+
+func (e *Enum[T]) set(ref Choice, byKey bool) error {
+	enum, err := e.Meta()
+
+	if err != nil {
+		return err
+	}
+
+	choice, ok := ref.lookup(enum.Choices, byKey)
+
+	if !ok {
+		return &ChoiceError{Enum: enum.Name, Code: ref.Code, Key: ref.Key}
+	}
+
+	e.Choice = choice
+
+	return nil
+}
+
+// Valid
+func (e Enum[T]) Valid() bool {
+	if enum, err := e.Meta(); err == nil {
+		_, ok := enum.Lookup(e.Choice.Code)
+
+		return ok
+	}
+
+	return false
+}
+
+// GORM
+func (e *Enum[T]) Scan(value any) error {
+	if value == nil {
+		*e = Enum[T]{}
+
+		return nil
+	}
+
+	ref, byKey, err := parseChoice(value)
+
+	if err != nil {
+		return err
+	}
+
+	if err := e.set(ref, byKey); err != nil {
+		return err
+	}
+
+	e.origin, e.loaded = e.Choice, true
+
+	return nil
+}
+
+func (e Enum[T]) Value() (driver.Value, error) {
+	if enum, err := e.Meta(); err == nil && enum.Storage == ChoiceStoreKey {
+		return e.Choice.Key, nil
+	}
+
+	return e.Choice.Code, nil
+}
+
+// JSON
+func (e Enum[T]) MarshalJSON() ([]byte, error) {
+	return e.MarshalJSONContext(context.Background())
+}
+
+// MarshalJSONContext 标签使用上下文中的语言
+func (e Enum[T]) MarshalJSONContext(ctx context.Context) ([]byte, error) {
+	return json.Marshal(e.Choice.Localize(ctx))
+}
+
+func (e *Enum[T]) UnmarshalJSON(data []byte) error {
+	ref, byKey, err := parseChoiceJSON(data)
+
+	if err != nil {
+		return err
+	}
+
+	return e.set(ref, byKey)
+}
+
+// String
+func (e Enum[T]) String() string {
+	return e.Choice.String()
+}
+
+// 状态变更
+func (e Enum[T]) choiceOrigin() (Choice, bool) {
+	return e.origin, e.loaded
+}
+
+func (e Enum[T]) choiceCurrent() Choice {
+	return e.Choice
+}
+
+func (e Enum[T]) choiceResolve(value any) (Choice, error) {
+	switch v := value.(type) {
+	case choiceTransitioner:
+		return v.choiceCurrent(), nil
+	case Choice:
+		return v, nil
+	}
+
+	ref, byKey, err := parseChoice(value)
+
+	if err != nil {
+		return Choice{}, err
+	}
+
+	var resolved Enum[T]
+
+	if err := resolved.set(ref, byKey); err != nil {
+		return Choice{}, err
+	}
+
+	return resolved.Choice, nil
+}
+
+func (e Enum[T]) choiceCheck(from Choice, to Choice) error {
+	enum, err := e.Meta()
+
+	if err != nil {
+		return err
+	}
+
+	return enum.Graph.Check(from, to)
+}
+
+func (e *Enum[T]) choiceCommit() {
+	e.origin, e.loaded = e.Choice, true
+}
@@ -1,6 +1,6 @@
 module github.com/cnjacker/datatype
 
-go 1.20
+go 1.21
 
 require (
 	github.com/deckarep/golang-set/v2 v2.1.0
@@ -8,10 +8,12 @@ require (
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/twpayne/go-geom v1.5.0
 	golang.org/x/crypto v0.6.0
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/gorm v1.24.5
 )
 
 require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.4 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 )
@@ -0,0 +1,285 @@
+package datatype
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	ErrKMSMasterKey = errors.New("datatype: invalid kms master key")
+)
+
+// 密钥管理服务, 使用主密钥包装/解包数据密钥
+type KMS interface {
+	WrapKey(key []byte) ([]byte, error)
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// ---------------------------------------------------------
+//
+//  LocalKMS
+//
+// ---------------------------------------------------------
+
+// 本地文件主密钥, 仅用于测试及开发环境
+type LocalKMS struct {
+	aead cipher.AEAD
+}
+
+// NewLocalKMS 读取主密钥文件(32字节原始数据或64位十六进制文本)
+func NewLocalKMS(path string) (*LocalKMS, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if v, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+		data = v
+	}
+
+	return NewLocalKMSFromKey(data)
+}
+
+// NewLocalKMSFromKey
+func NewLocalKMSFromKey(key []byte) (*LocalKMS, error) {
+	if len(key) != 32 {
+		return nil, ErrKMSMasterKey
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalKMS{aead: aead}, nil
+}
+
+// GenerateLocalKMSKey 生成主密钥文件
+func GenerateLocalKMSKey(path string) error {
+	key := make([]byte, 32)
+
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600)
+}
+
+func (k *LocalKMS) WrapKey(key []byte) ([]byte, error) {
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(key)+k.aead.Overhead())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return k.aead.Seal(nonce, nonce, key, nil), nil
+}
+
+func (k *LocalKMS) UnwrapKey(wrapped []byte) ([]byte, error) {
+	if len(wrapped) < k.aead.NonceSize() {
+		return nil, ErrEncryptCorrupted
+	}
+
+	v, err := k.aead.Open(nil, wrapped[:k.aead.NonceSize()], wrapped[k.aead.NonceSize():], nil)
+
+	if err != nil {
+		return nil, ErrEncryptCorrupted
+	}
+
+	return v, nil
+}
+
+// ---------------------------------------------------------
+//
+//  EnvelopeEncryptService
+//
+// ---------------------------------------------------------
+
+// 信封加密服务, 每个密文携带被包装的数据密钥, 解包后的数据密钥缓存在内存中
+type EnvelopeEncryptService struct {
+	// 密钥管理服务
+	KMS KMS
+	// 数据密钥有效期, 过期后生成新的数据密钥
+	KeyExpired time.Duration
+	// 解包数据密钥缓存
+	Cache *cache.Cache
+
+	mu      sync.Mutex
+	key     []byte
+	wrapped []byte
+	created time.Time
+}
+
+// NewEnvelopeEncryptService
+func NewEnvelopeEncryptService(kms KMS) *EnvelopeEncryptService {
+	return &EnvelopeEncryptService{
+		KMS:        kms,
+		KeyExpired: 24 * time.Hour,
+		Cache:      cache.New(time.Hour, 10*time.Minute),
+	}
+}
+
+func (es *EnvelopeEncryptService) Encode(value string) string {
+	v, _ := es.TryEncode(value)
+
+	return v
+}
+
+func (es *EnvelopeEncryptService) Decode(value string) string {
+	v, _ := es.TryDecode(value)
+
+	return v
+}
+
+func (es *EnvelopeEncryptService) Mask(value string) string {
+	return DefaultEncryptService{}.Mask(value)
+}
+
+func (es *EnvelopeEncryptService) TryEncode(value string) (string, error) {
+	return es.EncodeContext(context.Background(), EncryptContext{}, []byte(value))
+}
+
+func (es *EnvelopeEncryptService) TryDecode(value string) (string, error) {
+	v, err := es.DecodeContext(context.Background(), EncryptContext{}, value)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(v), nil
+}
+
+func (es *EnvelopeEncryptService) EncodeBytes(value []byte) (string, error) {
+	return es.EncodeContext(context.Background(), EncryptContext{}, value)
+}
+
+func (es *EnvelopeEncryptService) DecodeBytes(value string) ([]byte, error) {
+	return es.DecodeContext(context.Background(), EncryptContext{}, value)
+}
+
+// EncodeContext 密文格式: $env$<base64(wrapped key)>$<base64(nonce+ciphertext)>
+func (es *EnvelopeEncryptService) EncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	key, wrapped, err := es.dataKey()
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := aesSeal(key, value, ec.AssociatedData())
+
+	if err != nil {
+		return "", err
+	}
+
+	return "$env$" + base64.RawURLEncoding.EncodeToString(wrapped) + "$" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (es *EnvelopeEncryptService) DecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error) {
+	parts := strings.Split(value, "$")
+
+	if len(parts) != 4 || parts[0] != "" || parts[1] != "env" {
+		return nil, ErrEncryptCorrupted
+	}
+
+	wrapped, err := base64.RawURLEncoding.DecodeString(parts[2])
+
+	if err != nil {
+		return nil, ErrEncryptCorrupted
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[3])
+
+	if err != nil {
+		return nil, ErrEncryptCorrupted
+	}
+
+	key, err := es.unwrap(wrapped)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return aesOpen(key, data, ec.AssociatedData())
+}
+
+// dataKey 获取当前数据密钥, 不存在或过期时生成
+func (es *EnvelopeEncryptService) dataKey() ([]byte, []byte, error) {
+	es.mu.Lock()
+	defer es.mu.Unlock()
+
+	if es.key != nil && (es.KeyExpired <= 0 || time.Since(es.created) < es.KeyExpired) {
+		return es.key, es.wrapped, nil
+	}
+
+	key := make([]byte, 32)
+
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+
+	wrapped, err := es.KMS.WrapKey(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	es.key, es.wrapped, es.created = key, wrapped, time.Now()
+
+	if es.Cache != nil {
+		es.Cache.SetDefault(envelopeCacheKey(wrapped), key)
+	}
+
+	return key, wrapped, nil
+}
+
+// unwrap 解包数据密钥, 优先使用缓存
+func (es *EnvelopeEncryptService) unwrap(wrapped []byte) ([]byte, error) {
+	cacheKey := envelopeCacheKey(wrapped)
+
+	if es.Cache != nil {
+		if v, ok := es.Cache.Get(cacheKey); ok {
+			if key, ok := v.([]byte); ok {
+				return key, nil
+			}
+		}
+	}
+
+	key, err := es.KMS.UnwrapKey(wrapped)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if es.Cache != nil {
+		es.Cache.SetDefault(cacheKey, key)
+	}
+
+	return key, nil
+}
+
+// envelopeCacheKey
+func envelopeCacheKey(wrapped []byte) string {
+	sum := sha256.Sum256(wrapped)
+
+	return "envelope:" + hex.EncodeToString(sum[:])
+}
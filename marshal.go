@@ -0,0 +1,368 @@
+package datatype
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 根据上下文序列化JSON
+type ContextJSONMarshaler interface {
+	MarshalJSONContext(ctx context.Context) ([]byte, error)
+}
+
+var (
+	contextJSONMarshalerType = reflect.TypeOf((*ContextJSONMarshaler)(nil)).Elem()
+	jsonMarshalerType        = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType        = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// MarshalJSONContext 按encoding/json的规则序列化, 实现ContextJSONMarshaler的字段使用ctx序列化
+func MarshalJSONContext(ctx context.Context, v any) ([]byte, error) {
+	tree, err := contextJSONValue(ctx, reflect.ValueOf(v))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(tree)
+}
+
+type contextJSONField struct {
+	name   string
+	depth  int
+	tagged bool
+	// 不输出, 但参与同名字段的判断, 如omitempty的空值及nil嵌入指针中的字段
+	absent bool
+	value  any
+}
+
+type contextJSONObject []contextJSONField
+
+func (o contextJSONObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := json.Marshal(field.name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := json.Marshal(field.value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// contextJSONValue
+func contextJSONValue(ctx context.Context, rv reflect.Value) (any, error) {
+	if !rv.IsValid() {
+		return nil, nil
+	}
+
+	if (rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return nil, nil
+	}
+
+	if rv.Type().Implements(contextJSONMarshalerType) {
+		return contextJSONMarshal(ctx, rv.Interface().(ContextJSONMarshaler))
+	}
+
+	if rv.CanAddr() && rv.Addr().Type().Implements(contextJSONMarshalerType) {
+		return contextJSONMarshal(ctx, rv.Addr().Interface().(ContextJSONMarshaler))
+	}
+
+	if rv.Type().Implements(jsonMarshalerType) || rv.Type().Implements(textMarshalerType) {
+		return rv.Interface(), nil
+	}
+
+	if rv.CanAddr() && (rv.Addr().Type().Implements(jsonMarshalerType) || rv.Addr().Type().Implements(textMarshalerType)) {
+		return rv.Addr().Interface(), nil
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		return contextJSONValue(ctx, rv.Elem())
+	case reflect.Struct:
+		return contextJSONStruct(ctx, rv, 0, false)
+	case reflect.Map:
+		return contextJSONMap(ctx, rv)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return nil, nil
+		}
+
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Interface(), nil
+		}
+
+		fallthrough
+	case reflect.Array:
+		data := make([]any, rv.Len())
+
+		for i := 0; i < rv.Len(); i++ {
+			v, err := contextJSONValue(ctx, rv.Index(i))
+
+			if err != nil {
+				return nil, err
+			}
+
+			data[i] = v
+		}
+
+		return data, nil
+	default:
+		return rv.Interface(), nil
+	}
+}
+
+// contextJSONMarshal
+func contextJSONMarshal(ctx context.Context, m ContextJSONMarshaler) (any, error) {
+	v, err := m.MarshalJSONContext(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(v), nil
+}
+
+// contextJSONStruct absent为true时只收集字段名
+func contextJSONStruct(ctx context.Context, rv reflect.Value, depth int, absent bool) (contextJSONObject, error) {
+	var object contextJSONObject
+
+	for i := 0; i < rv.NumField(); i++ {
+		sf := rv.Type().Field(i)
+		fv := rv.Field(i)
+
+		tag := sf.Tag.Get("json")
+
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if sf.Anonymous && name == "" {
+			t := sf.Type
+
+			if t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+
+			if t.Kind() == reflect.Struct {
+				nested := absent
+
+				if fv.Kind() == reflect.Pointer {
+					if fv.IsNil() {
+						fv = reflect.New(t)
+						nested = true
+					}
+
+					fv = fv.Elem()
+				}
+
+				embedded, err := contextJSONStruct(ctx, fv, depth+1, nested)
+
+				if err != nil {
+					return nil, err
+				}
+
+				object = append(object, embedded...)
+
+				continue
+			}
+		}
+
+		if !sf.IsExported() {
+			continue
+		}
+
+		tagged := name != ""
+
+		if !tagged {
+			name = sf.Name
+		}
+
+		if absent || contextJSONHasOption(opts, "omitempty") && contextJSONIsEmpty(fv) {
+			object = append(object, contextJSONField{name: name, depth: depth, tagged: tagged, absent: true})
+
+			continue
+		}
+
+		v, err := contextJSONValue(ctx, fv)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if contextJSONHasOption(opts, "string") {
+			switch fv.Kind() {
+			case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Float32, reflect.Float64, reflect.String:
+				data, err := json.Marshal(v)
+
+				if err != nil {
+					return nil, err
+				}
+
+				v = string(data)
+			}
+		}
+
+		object = append(object, contextJSONField{name: name, depth: depth, tagged: tagged, value: v})
+	}
+
+	if depth > 0 {
+		return object, nil
+	}
+
+	// 同名字段按encoding/json的规则处理: 保留层级最浅的字段, 同一层级有多个时
+	// 保留唯一带标签的字段, 否则全部忽略
+	var fields contextJSONObject
+
+	for i, field := range object {
+		if !field.absent && contextJSONDominant(object, field.name) == i {
+			fields = append(fields, field)
+		}
+	}
+
+	return fields, nil
+}
+
+// contextJSONDominant 同名字段中生效的字段下标, 没有时返回-1
+func contextJSONDominant(object contextJSONObject, name string) int {
+	dominant := -1
+	count := 0
+	tagged := 0
+
+	for i, field := range object {
+		if field.name != name {
+			continue
+		}
+
+		if dominant < 0 || field.depth < object[dominant].depth {
+			dominant, count, tagged = i, 0, 0
+		}
+
+		if field.depth == object[dominant].depth {
+			count++
+
+			if field.tagged {
+				if tagged == 0 {
+					dominant = i
+				}
+
+				tagged++
+			}
+		}
+	}
+
+	if count > 1 && tagged != 1 {
+		return -1
+	}
+
+	return dominant
+}
+
+// contextJSONMap
+func contextJSONMap(ctx context.Context, rv reflect.Value) (any, error) {
+	if rv.IsNil() {
+		return nil, nil
+	}
+
+	data := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+
+	for iter.Next() {
+		var key string
+
+		k := iter.Key()
+
+		switch {
+		case k.Kind() == reflect.String:
+			key = k.String()
+		case k.Type().Implements(textMarshalerType):
+			text, err := k.Interface().(encoding.TextMarshaler).MarshalText()
+
+			if err != nil {
+				return nil, err
+			}
+
+			key = string(text)
+		case k.CanInt():
+			key = strconv.FormatInt(k.Int(), 10)
+		case k.CanUint():
+			key = strconv.FormatUint(k.Uint(), 10)
+		default:
+			return nil, &json.UnsupportedTypeError{Type: rv.Type()}
+		}
+
+		v, err := contextJSONValue(ctx, iter.Value())
+
+		if err != nil {
+			return nil, err
+		}
+
+		data[key] = v
+	}
+
+	return data, nil
+}
+
+// contextJSONHasOption
+func contextJSONHasOption(opts string, option string) bool {
+	for opts != "" {
+		var name string
+
+		name, opts, _ = strings.Cut(opts, ",")
+
+		if name == option {
+			return true
+		}
+	}
+
+	return false
+}
+
+// contextJSONIsEmpty
+func contextJSONIsEmpty(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return rv.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return rv.Float() == 0
+	case reflect.Interface, reflect.Pointer:
+		return rv.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,137 @@
+package datatype
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type marshalInner struct {
+	Name  string `json:"name"`
+	Inner int
+}
+
+type marshalOther struct {
+	Name  string
+	Inner int
+}
+
+type marshalTagged struct {
+	Value string `json:"value"`
+}
+
+type marshalUntagged struct {
+	Value string
+}
+
+type marshalConflicts struct {
+	marshalInner
+	*marshalOther
+	marshalTagged
+	marshalUntagged
+	ID int `json:"id,string"`
+}
+
+type marshalShadow struct {
+	marshalInner
+	Name string
+}
+
+type marshalOmitShadow struct {
+	marshalTagged
+	Value string `json:"value,omitempty"`
+}
+
+type marshalAmbiguous struct {
+	marshalTagged
+	Other marshalTagged `json:"other"`
+	Dup   struct {
+		marshalTagged
+		marshalUntagged
+	} `json:"dup"`
+	Drop struct {
+		marshalInner
+		marshalOther
+	} `json:"drop"`
+}
+
+type marshalOptions struct {
+	Omit     string            `json:"omit,omitempty"`
+	Skip     string            `json:"-"`
+	Dash     string            `json:"-,"`
+	Count    int64             `json:",string"`
+	Bytes    []byte            `json:"bytes"`
+	Nil      []int             `json:"nil"`
+	Map      map[int]string    `json:"map"`
+	Time     time.Time         `json:"time"`
+	Pointer  *marshalTagged    `json:"pointer"`
+	Iface    any               `json:"iface"`
+	Embedded map[string]string `json:"embedded,omitempty"`
+	private  string
+}
+
+// 不含ContextJSONMarshaler字段时输出应与encoding/json一致
+func TestMarshalJSONContextMatchesEncodingJSON(t *testing.T) {
+	cases := []any{
+		marshalConflicts{
+			marshalInner:    marshalInner{Name: "inner", Inner: 1},
+			marshalOther:    &marshalOther{Name: "other", Inner: 2},
+			marshalTagged:   marshalTagged{Value: "tagged"},
+			marshalUntagged: marshalUntagged{Value: "untagged"},
+			ID:              7,
+		},
+		marshalConflicts{},
+		marshalShadow{marshalInner: marshalInner{Name: "inner"}, Name: "outer"},
+		marshalAmbiguous{},
+		marshalOmitShadow{marshalTagged: marshalTagged{Value: "hidden"}},
+		marshalOptions{
+			Skip:    "skip",
+			Dash:    "dash",
+			Count:   42,
+			Bytes:   []byte("bytes"),
+			Map:     map[int]string{1: "a", 2: "b"},
+			Time:    time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+			Pointer: &marshalTagged{Value: "p"},
+			Iface:   marshalInner{Name: "i"},
+			private: "private",
+		},
+		[]any{1, "a", nil, marshalShadow{}},
+	}
+
+	for _, v := range cases {
+		want, err := json.Marshal(v)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := MarshalJSONContext(context.Background(), v)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != string(want) {
+			t.Errorf("MarshalJSONContext(%T)\n got: %s\nwant: %s", v, got, want)
+		}
+	}
+}
+
+type marshalPolicy struct {
+	Phone Encrypt `json:"phone"`
+}
+
+func TestMarshalJSONContextPolicy(t *testing.T) {
+	v := marshalPolicy{Phone: Encrypt("13800138000")}
+
+	got, err := MarshalJSONContext(WithEncryptJSONPolicy(context.Background(), EncryptJSONPlaintext), v)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"phone":"13800138000"}`; string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
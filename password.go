@@ -3,29 +3,131 @@ package datatype
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+var passwordRedacted = strings.Repeat("*", 8)
+
 type Password struct {
 	hash []byte
 }
 
-// Update
-func (p *Password) Update(password string) {
-	p.hash, _ = bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// Update 校验密码策略后生成哈希, userInputs为用户名等不应与密码相似的内容; 失败时保留原哈希
+func (p *Password) Update(password string, userInputs ...string) error {
+	return p.UpdateWithHistory(password, nil, userInputs...)
 }
 
-// Compare
+// UpdateWithHistory 同Update, 并拒绝使用当前及历史密码, 成功后将原哈希加入历史
+func (p *Password) UpdateWithHistory(password string, history *PasswordHistory, userInputs ...string) error {
+	if err := PasswordOptions.Policy.Validate(password, userInputs...); err != nil {
+		return err
+	}
+
+	if history != nil {
+		if ok, _ := passwordVerify(p.hash, password); ok || history.Contains(password) {
+			return &PasswordPolicyError{Violations: []PasswordViolation{PasswordReused}}
+		}
+	}
+
+	hash := p.hash
+
+	if err := p.rehash(password); err != nil {
+		return err
+	}
+
+	if history != nil && len(hash) > 0 {
+		history.Push(hash)
+	}
+
+	return nil
+}
+
+// Import 导入已有哈希, 如旧系统的MD5/SHA-256哈希
+func (p *Password) Import(hash []byte) error {
+	if _, _, found := passwordHasherOf(hash); !found {
+		return ErrPasswordHash
+	}
+
+	p.hash = hash
+
+	return nil
+}
+
+// Compare 校验密码, 旧格式哈希校验成功后自动升级
 func (p *Password) Compare(password string) bool {
-	return bcrypt.CompareHashAndPassword(p.hash, []byte(password)) == nil
+	ok, _ := p.CompareAndUpgrade(password)
+
+	return ok
+}
+
+// CompareLimited 使用PasswordOptions.Limiter限制key(如用户名、IP)的尝试次数, 锁定时返回*PasswordLockedError
+func (p *Password) CompareLimited(key string, password string) (bool, error) {
+	limiter := PasswordOptions.Limiter
+
+	if limiter == nil {
+		return p.Compare(password), nil
+	}
+
+	if retryAfter, ok := limiter.Allow(key); !ok {
+		return false, &PasswordLockedError{RetryAfter: retryAfter}
+	}
+
+	if p.Compare(password) {
+		limiter.Success(key)
+
+		return true, nil
+	}
+
+	limiter.Failure(key)
+
+	return false, nil
+}
+
+// CompareAndUpgrade 校验密码, 需要重新哈希时使用当前算法更新哈希, 并返回是否已更新
+func (p *Password) CompareAndUpgrade(password string) (bool, bool) {
+	ok, rehash := p.CompareWithRehash(password)
+
+	if ok && rehash {
+		return true, p.rehash(password) == nil
+	}
+
+	return ok, false
+}
+
+// CompareWithRehash 校验密码, 并返回哈希是否需要使用当前算法重新生成
+func (p *Password) CompareWithRehash(password string) (bool, bool) {
+	return passwordVerify(p.hash, password)
+}
+
+// rehash 使用当前算法生成哈希
+func (p *Password) rehash(password string) error {
+	hash, err := PasswordOptions.Hasher.Hash(passwordPeppered(password))
+
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return &PasswordPolicyError{Violations: []PasswordViolation{PasswordTooLong}}
+		}
+
+		return fmt.Errorf("datatype: hash password: %w", err)
+	}
+
+	p.hash = hash
+
+	return nil
 }
 
 // GORM
 func (p *Password) Scan(value any) error {
-	if v, ok := value.([]byte); ok {
+	switch v := value.(type) {
+	case []byte:
 		p.hash = v
+	case string:
+		p.hash = []byte(v)
 	}
 
 	return nil
@@ -37,14 +139,38 @@ func (p Password) Value() (driver.Value, error) {
 
 // JSON
 func (p Password) MarshalJSON() ([]byte, error) {
-	return json.Marshal(strings.Repeat("*", 8))
+	return json.Marshal(passwordRedacted)
 }
 
+// UnmarshalJSON 开启PasswordOptions.UnmarshalPlaintext时将明文哈希后保存, 空值及掩码忽略
 func (p *Password) UnmarshalJSON(data []byte) error {
-	return nil
+	if !PasswordOptions.UnmarshalPlaintext {
+		return nil
+	}
+
+	var s string
+
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+
+	if s == "" || s == passwordRedacted {
+		return nil
+	}
+
+	return p.Update(s)
 }
 
 // String
 func (p Password) String() string {
-	return string(p.hash)
+	return passwordRedacted
+}
+
+func (p Password) GoString() string {
+	return "datatype.Password{" + passwordRedacted + "}"
+}
+
+// LogValue
+func (p Password) LogValue() slog.Value {
+	return slog.StringValue(passwordRedacted)
 }
@@ -0,0 +1,475 @@
+package datatype
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	ErrPasswordHash = errors.New("datatype: unsupported password hash")
+)
+
+// 密码哈希算法
+type PasswordHasher interface {
+	// 生成哈希
+	Hash(password []byte) ([]byte, error)
+	// 是否为本算法生成的哈希
+	Identify(hash []byte) bool
+	// 校验密码
+	Verify(hash []byte, password []byte) (bool, error)
+	// 哈希参数是否与当前配置不一致
+	NeedsRehash(hash []byte) bool
+}
+
+// ---------------------------------------------------------
+//
+//  BcryptHasher
+//
+// ---------------------------------------------------------
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password []byte) ([]byte, error) {
+	cost := h.Cost
+
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+
+	return bcrypt.GenerateFromPassword(password, cost)
+}
+
+func (h BcryptHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) ||
+		bytes.HasPrefix(hash, []byte("$2b$")) ||
+		bytes.HasPrefix(hash, []byte("$2y$"))
+}
+
+func (h BcryptHasher) Verify(hash []byte, password []byte) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(hash, password)
+
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (h BcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+
+	if err != nil {
+		return true
+	}
+
+	if h.Cost == 0 {
+		return cost != bcrypt.DefaultCost
+	}
+
+	return cost != h.Cost
+}
+
+// ---------------------------------------------------------
+//
+//  Argon2idHasher
+//
+// ---------------------------------------------------------
+
+// PHC格式: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>, 为0的参数使用DefaultArgon2idHasher的配置
+type Argon2idHasher struct {
+	// 内存(KiB)
+	Memory uint32
+	// 迭代次数
+	Iterations uint32
+	// 并行度
+	Parallelism uint8
+	// 盐长度
+	SaltLength int
+	// 哈希长度
+	KeyLength uint32
+}
+
+// DefaultArgon2idHasher RFC 9106推荐的低内存配置
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{
+		Memory:      64 * 1024,
+		Iterations:  3,
+		Parallelism: 4,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+// defaults 为0的参数使用DefaultArgon2idHasher的配置
+func (h Argon2idHasher) defaults() Argon2idHasher {
+	d := DefaultArgon2idHasher()
+
+	if h.Memory == 0 {
+		h.Memory = d.Memory
+	}
+
+	if h.Iterations == 0 {
+		h.Iterations = d.Iterations
+	}
+
+	if h.Parallelism == 0 {
+		h.Parallelism = d.Parallelism
+	}
+
+	if h.SaltLength == 0 {
+		h.SaltLength = d.SaltLength
+	}
+
+	if h.KeyLength == 0 {
+		h.KeyLength = d.KeyLength
+	}
+
+	return h
+}
+
+func (h Argon2idHasher) Hash(password []byte) ([]byte, error) {
+	h = h.defaults()
+
+	if h.SaltLength < 0 {
+		return nil, fmt.Errorf("datatype: invalid argon2id salt length %d", h.SaltLength)
+	}
+
+	salt := make([]byte, h.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+
+	return []byte(fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.Memory,
+		h.Iterations,
+		h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (h Argon2idHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
+
+func (h Argon2idHasher) Verify(hash []byte, password []byte) (bool, error) {
+	params, salt, key, err := h.parse(hash)
+
+	if err != nil {
+		return false, err
+	}
+
+	v := argon2.IDKey(password, salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(v, key) == 1, nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, salt, key, err := h.parse(hash)
+
+	if err != nil {
+		return true
+	}
+
+	h = h.defaults()
+
+	return params.Memory != h.Memory ||
+		params.Iterations != h.Iterations ||
+		params.Parallelism != h.Parallelism ||
+		len(salt) != h.SaltLength ||
+		uint32(len(key)) != h.KeyLength
+}
+
+func (h Argon2idHasher) parse(hash []byte) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+	var version int
+
+	parts := strings.Split(string(hash), "$")
+
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	// t或p为0时argon2.IDKey会panic
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil ||
+		params.Iterations == 0 || params.Parallelism == 0 {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+
+	if err != nil {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// ---------------------------------------------------------
+//
+//  ScryptHasher
+//
+// ---------------------------------------------------------
+
+// PHC格式: $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+type ScryptHasher struct {
+	// CPU/内存成本(log2 N)
+	LogN uint8
+	// 块大小
+	R int
+	// 并行度
+	P int
+	// 盐长度
+	SaltLength int
+	// 哈希长度
+	KeyLength int
+}
+
+// DefaultScryptHasher
+func DefaultScryptHasher() ScryptHasher {
+	return ScryptHasher{
+		LogN:       15,
+		R:          8,
+		P:          1,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+func (h ScryptHasher) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, h.SaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key, err := scrypt.Key(password, salt, 1<<h.LogN, h.R, h.P, h.KeyLength)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(fmt.Sprintf(
+		"$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		h.LogN,
+		h.R,
+		h.P,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (h ScryptHasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$scrypt$"))
+}
+
+func (h ScryptHasher) Verify(hash []byte, password []byte) (bool, error) {
+	params, salt, key, err := h.parse(hash)
+
+	if err != nil {
+		return false, err
+	}
+
+	v, err := scrypt.Key(password, salt, 1<<params.LogN, params.R, params.P, len(key))
+
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare(v, key) == 1, nil
+}
+
+func (h ScryptHasher) NeedsRehash(hash []byte) bool {
+	params, salt, key, err := h.parse(hash)
+
+	if err != nil {
+		return true
+	}
+
+	return params.LogN != h.LogN ||
+		params.R != h.R ||
+		params.P != h.P ||
+		len(salt) != h.SaltLength ||
+		len(key) != h.KeyLength
+}
+
+func (h ScryptHasher) parse(hash []byte) (ScryptHasher, []byte, []byte, error) {
+	var params ScryptHasher
+
+	parts := strings.Split(string(hash), "$")
+
+	if len(parts) != 5 || parts[1] != "scrypt" {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &params.LogN, &params.R, &params.P); err != nil || params.LogN >= 32 {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+
+	if err != nil {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[4])
+
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrPasswordHash
+	}
+
+	return params, salt, key, nil
+}
+
+// ---------------------------------------------------------
+//
+//  PasswordOptions
+//
+// ---------------------------------------------------------
+
+type PasswordConfig struct {
+	// 新密码使用的哈希算法
+	Hasher PasswordHasher
+	// 可用于校验旧哈希的算法
+	Hashers []PasswordHasher
+	// 旧系统哈希算法, 校验成功后自动升级为当前算法
+	Legacy []PasswordHasher
+	// 全局密钥, 哈希前以HMAC-SHA256作用于密码
+	Pepper []byte
+	// 允许校验启用Pepper之前生成的哈希, 校验成功后升级
+	PepperFallback bool
+	// 密码策略, 默认不校验, 启用后Update对不符合策略的密码返回*PasswordPolicyError
+	Policy PasswordPolicy
+	// 历史密码保留数量
+	HistoryDepth int
+	// JSON反序列化时接受明文密码并哈希
+	UnmarshalPlaintext bool
+	// 尝试次数限制, 用于CompareLimited
+	Limiter PasswordLimiter
+}
+
+var PasswordOptions = PasswordConfig{
+	Hasher: BcryptHasher{Cost: bcrypt.DefaultCost},
+	Hashers: []PasswordHasher{
+		BcryptHasher{},
+		DefaultArgon2idHasher(),
+		DefaultScryptHasher(),
+	},
+	HistoryDepth: 5,
+}
+
+// passwordHasherOf 查找生成该哈希的算法, 并返回是否为旧系统算法
+func passwordHasherOf(hash []byte) (PasswordHasher, bool, bool) {
+	if PasswordOptions.Hasher.Identify(hash) {
+		return PasswordOptions.Hasher, false, true
+	}
+
+	for _, hasher := range PasswordOptions.Hashers {
+		if hasher.Identify(hash) {
+			return hasher, false, true
+		}
+	}
+
+	for _, hasher := range PasswordOptions.Legacy {
+		if hasher.Identify(hash) {
+			return hasher, true, true
+		}
+	}
+
+	return nil, false, false
+}
+
+var passwordDummy struct {
+	sync.Mutex
+	hash []byte
+}
+
+// passwordDummyHash 空哈希使用的占位哈希, 保证校验耗时与正常哈希一致
+func passwordDummyHash() []byte {
+	passwordDummy.Lock()
+	defer passwordDummy.Unlock()
+
+	if passwordDummy.hash == nil || !PasswordOptions.Hasher.Identify(passwordDummy.hash) || PasswordOptions.Hasher.NeedsRehash(passwordDummy.hash) {
+		dummy := make([]byte, 16)
+
+		if _, err := rand.Read(dummy); err != nil {
+			return nil
+		}
+
+		passwordDummy.hash, _ = PasswordOptions.Hasher.Hash(dummy)
+	}
+
+	return passwordDummy.hash
+}
+
+// passwordVerify 校验密码, 并返回哈希是否需要使用当前算法重新生成
+func passwordVerify(hash []byte, password string) (bool, bool) {
+	hasher, legacy, found := passwordHasherOf(hash)
+
+	if !found {
+		if dummy := passwordDummyHash(); dummy != nil {
+			_, _ = PasswordOptions.Hasher.Verify(dummy, passwordPeppered(password))
+		}
+
+		return false, false
+	}
+
+	if legacy {
+		ok, err := hasher.Verify(hash, []byte(password))
+
+		return ok && err == nil, ok && err == nil
+	}
+
+	if ok, err := hasher.Verify(hash, passwordPeppered(password)); err == nil && ok {
+		return true, !PasswordOptions.Hasher.Identify(hash) || PasswordOptions.Hasher.NeedsRehash(hash)
+	}
+
+	if len(PasswordOptions.Pepper) > 0 && PasswordOptions.PepperFallback {
+		if ok, err := hasher.Verify(hash, []byte(password)); err == nil && ok {
+			return true, true
+		}
+	}
+
+	return false, false
+}
+
+// passwordPeppered 使用Pepper处理密码
+func passwordPeppered(password string) []byte {
+	if len(PasswordOptions.Pepper) == 0 {
+		return []byte(password)
+	}
+
+	mac := hmac.New(sha256.New, PasswordOptions.Pepper)
+	mac.Write([]byte(password))
+
+	return []byte(base64.RawStdEncoding.EncodeToString(mac.Sum(nil)))
+}
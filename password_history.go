@@ -0,0 +1,122 @@
+package datatype
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// 历史密码哈希, PostgreSQL存储为bytea[], 其他数据库存储为JSON
+type PasswordHistory [][]byte
+
+// Contains 密码是否与历史密码相同
+func (h PasswordHistory) Contains(password string) bool {
+	found := false
+
+	// 遍历全部历史, 避免通过耗时推断命中位置
+	for _, hash := range h {
+		if ok, _ := passwordVerify(hash, password); ok {
+			found = true
+		}
+	}
+
+	return found
+}
+
+// Push 加入哈希, 并按PasswordOptions.HistoryDepth保留最近的记录
+func (h *PasswordHistory) Push(hash []byte) {
+	v := append(PasswordHistory{hash}, *h...)
+
+	if depth := PasswordOptions.HistoryDepth; depth >= 0 && len(v) > depth {
+		v = v[:depth]
+	}
+
+	*h = v
+}
+
+// GORM
+func (h PasswordHistory) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "bytea[]"
+	case "sqlserver":
+		return "NVARCHAR(MAX)"
+	default:
+		return "JSON"
+	}
+}
+
+func (h PasswordHistory) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	if db.Dialector.Name() == "postgres" {
+		return gorm.Expr("?", pq.ByteaArray(h))
+	}
+
+	data := make([]string, len(h))
+
+	for i, hash := range h {
+		data[i] = string(hash)
+	}
+
+	if v, err := json.Marshal(data); err == nil {
+		return gorm.Expr("?", string(v))
+	}
+
+	return gorm.Expr("NULL")
+}
+
+func (h *PasswordHistory) Scan(value any) error {
+	var bytes []byte
+
+	switch v := value.(type) {
+	case nil:
+		*h = nil
+
+		return nil
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into PasswordHistory", value)
+	}
+
+	if len(bytes) > 0 && bytes[0] == '[' {
+		var data []string
+
+		if err := json.Unmarshal(bytes, &data); err != nil {
+			return err
+		}
+
+		v := make(PasswordHistory, len(data))
+
+		for i, hash := range data {
+			v[i] = []byte(hash)
+		}
+
+		*h = v
+
+		return nil
+	}
+
+	var objs pq.ByteaArray
+
+	if err := objs.Scan(bytes); err != nil {
+		return err
+	}
+
+	*h = PasswordHistory(objs)
+
+	return nil
+}
+
+// JSON
+func (h PasswordHistory) MarshalJSON() ([]byte, error) {
+	data := make([]Password, len(h))
+
+	return json.Marshal(data)
+}
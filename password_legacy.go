@@ -0,0 +1,112 @@
+package datatype
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"hash"
+	"regexp"
+	"strings"
+)
+
+// ---------------------------------------------------------
+//
+//  SHA256Hasher
+//
+// ---------------------------------------------------------
+
+// 旧系统加盐SHA-256, 格式: $sha256$<salt>$<hex digest>
+type SHA256Hasher struct {
+	// 盐在密码之后, 即sha256(password + salt)
+	SaltAfter bool
+}
+
+func (h SHA256Hasher) Hash(password []byte) ([]byte, error) {
+	return legacyHash("sha256", sha256.New(), password, h.SaltAfter)
+}
+
+func (h SHA256Hasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$sha256$"))
+}
+
+func (h SHA256Hasher) Verify(hash []byte, password []byte) (bool, error) {
+	return legacyVerify("sha256", sha256.New(), hash, password, h.SaltAfter)
+}
+
+func (h SHA256Hasher) NeedsRehash(hash []byte) bool {
+	return true
+}
+
+// ---------------------------------------------------------
+//
+//  MD5Hasher
+//
+// ---------------------------------------------------------
+
+var legacyMD5Regexp = regexp.MustCompile(`^[0-9a-fA-F]{32}$`)
+
+// 旧系统MD5, 格式: $md5$<salt>$<hex digest>, 或无盐的32位十六进制摘要
+type MD5Hasher struct {
+	// 盐在密码之后, 即md5(password + salt)
+	SaltAfter bool
+}
+
+func (h MD5Hasher) Hash(password []byte) ([]byte, error) {
+	return legacyHash("md5", md5.New(), password, h.SaltAfter)
+}
+
+func (h MD5Hasher) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$md5$")) || legacyMD5Regexp.Match(hash)
+}
+
+func (h MD5Hasher) Verify(hash []byte, password []byte) (bool, error) {
+	if legacyMD5Regexp.Match(hash) {
+		hash = []byte("$md5$$" + strings.ToLower(string(hash)))
+	}
+
+	return legacyVerify("md5", md5.New(), hash, password, h.SaltAfter)
+}
+
+func (h MD5Hasher) NeedsRehash(hash []byte) bool {
+	return true
+}
+
+// legacyHash
+func legacyHash(name string, h hash.Hash, password []byte, saltAfter bool) ([]byte, error) {
+	salt := make([]byte, 8)
+
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return []byte("$" + name + "$" + hex.EncodeToString(salt) + "$" + legacyDigest(h, []byte(hex.EncodeToString(salt)), password, saltAfter)), nil
+}
+
+// legacyVerify
+func legacyVerify(name string, h hash.Hash, hash []byte, password []byte, saltAfter bool) (bool, error) {
+	parts := strings.Split(string(hash), "$")
+
+	if len(parts) != 4 || parts[1] != name {
+		return false, ErrPasswordHash
+	}
+
+	digest := legacyDigest(h, []byte(parts[2]), password, saltAfter)
+
+	return subtle.ConstantTimeCompare([]byte(digest), []byte(strings.ToLower(parts[3]))) == 1, nil
+}
+
+// legacyDigest
+func legacyDigest(h hash.Hash, salt []byte, password []byte, saltAfter bool) string {
+	if saltAfter {
+		h.Write(password)
+		h.Write(salt)
+	} else {
+		h.Write(salt)
+		h.Write(password)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
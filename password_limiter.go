@@ -0,0 +1,157 @@
+package datatype
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+var (
+	ErrPasswordLocked = errors.New("datatype: too many password attempts")
+)
+
+// 尝试次数过多被锁定
+type PasswordLockedError struct {
+	// 需要等待的时间
+	RetryAfter time.Duration
+}
+
+func (e *PasswordLockedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrPasswordLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *PasswordLockedError) Is(target error) bool {
+	return target == ErrPasswordLocked
+}
+
+// 密码尝试次数限制
+type PasswordLimiter interface {
+	// 是否允许尝试, 不允许时返回需要等待的时间
+	Allow(key string) (time.Duration, bool)
+	// 记录失败
+	Failure(key string)
+	// 记录成功
+	Success(key string)
+}
+
+// ---------------------------------------------------------
+//
+//  MemoryPasswordLimiter
+//
+// ---------------------------------------------------------
+
+type memoryPasswordLimiterEntry struct {
+	failures int
+	lockouts int
+	until    time.Time
+}
+
+// 内存尝试次数限制, 连续失败达到MaxAttempts后锁定, 每次锁定时长翻倍
+type MemoryPasswordLimiter struct {
+	// 触发锁定的连续失败次数
+	MaxAttempts int
+	// 首次锁定时长
+	Lockout time.Duration
+	// 最长锁定时长, 0表示不限制
+	MaxLockout time.Duration
+	// 失败记录在最后一次失败(或锁定结束)后的保留时间, 默认24小时
+	TTL time.Duration
+
+	mu      sync.Mutex
+	entries *cache.Cache
+}
+
+// NewMemoryPasswordLimiter
+func NewMemoryPasswordLimiter(maxAttempts int, lockout time.Duration, maxLockout time.Duration) *MemoryPasswordLimiter {
+	return &MemoryPasswordLimiter{
+		MaxAttempts: maxAttempts,
+		Lockout:     lockout,
+		MaxLockout:  maxLockout,
+	}
+}
+
+func (l *MemoryPasswordLimiter) Allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if entry := l.entry(key); entry != nil {
+		if wait := time.Until(entry.until); wait > 0 {
+			return wait, false
+		}
+	}
+
+	return 0, true
+}
+
+func (l *MemoryPasswordLimiter) Failure(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry := l.entry(key)
+
+	if entry == nil {
+		entry = &memoryPasswordLimiterEntry{}
+	}
+
+	entry.failures++
+
+	if entry.failures >= l.MaxAttempts {
+		lockout := l.Lockout << entry.lockouts
+
+		switch {
+		case l.MaxLockout > 0 && (lockout <= 0 || lockout > l.MaxLockout):
+			lockout = l.MaxLockout
+		case entry.lockouts > 0 && (lockout <= 0 || lockout>>entry.lockouts != l.Lockout):
+			// 不限制时长时移位溢出, 保持上一次的锁定时长
+			lockout = l.Lockout << (entry.lockouts - 1)
+		default:
+			entry.lockouts++
+		}
+
+		entry.failures = 0
+		entry.until = time.Now().Add(lockout)
+	}
+
+	// 每条记录单独设置过期时间, 避免MaxLockout为0时记录永不过期
+	ttl := l.TTL
+
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	if wait := time.Until(entry.until); wait > 0 {
+		ttl += wait
+	}
+
+	l.cache().Set(key, entry, ttl)
+}
+
+func (l *MemoryPasswordLimiter) Success(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cache().Delete(key)
+}
+
+// cache 延迟创建, 直接使用结构体字面量时同样可用, 调用方需持有mu
+func (l *MemoryPasswordLimiter) cache() *cache.Cache {
+	if l.entries == nil {
+		l.entries = cache.New(cache.NoExpiration, 10*time.Minute)
+	}
+
+	return l.entries
+}
+
+// entry
+func (l *MemoryPasswordLimiter) entry(key string) *memoryPasswordLimiterEntry {
+	if v, ok := l.cache().Get(key); ok {
+		if entry, ok := v.(*memoryPasswordLimiterEntry); ok {
+			return entry
+		}
+	}
+
+	return nil
+}
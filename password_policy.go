@@ -0,0 +1,206 @@
+package datatype
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	mapset "github.com/deckarep/golang-set/v2"
+)
+
+// 密码策略违规项
+type PasswordViolation string
+
+const (
+	PasswordTooShort      PasswordViolation = "too_short"
+	PasswordTooLong       PasswordViolation = "too_long"
+	PasswordMissingLower  PasswordViolation = "missing_lower"
+	PasswordMissingUpper  PasswordViolation = "missing_upper"
+	PasswordMissingDigit  PasswordViolation = "missing_digit"
+	PasswordMissingSymbol PasswordViolation = "missing_symbol"
+	PasswordBanned        PasswordViolation = "banned"
+	PasswordSimilar       PasswordViolation = "similar"
+	PasswordReused        PasswordViolation = "reused"
+)
+
+// 密码策略校验错误
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	var data []string
+
+	for _, v := range e.Violations {
+		data = append(data, string(v))
+	}
+
+	return "datatype: password violates policy: " + strings.Join(data, ", ")
+}
+
+// Has
+func (e *PasswordPolicyError) Has(violation PasswordViolation) bool {
+	for _, v := range e.Violations {
+		if v == violation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// 密码策略
+type PasswordPolicy struct {
+	// 最小长度(字符)
+	MinLength int
+	// 最大长度(字符), 0表示不限制
+	MaxLength int
+	// 需要小写字母
+	RequireLower bool
+	// 需要大写字母
+	RequireUpper bool
+	// 需要数字
+	RequireDigit bool
+	// 需要特殊字符
+	RequireSymbol bool
+	// 禁用密码(小写)
+	Banned mapset.Set[string]
+	// 与用户名等输入的最大相似度(0~1), 0表示不校验
+	MaxSimilarity float64
+}
+
+// Validate 校验密码, userInputs为用户名、邮箱等不应与密码相似的内容
+func (pp PasswordPolicy) Validate(password string, userInputs ...string) error {
+	var violations []PasswordViolation
+
+	size := utf8.RuneCountInString(password)
+
+	if size < pp.MinLength {
+		violations = append(violations, PasswordTooShort)
+	}
+
+	if pp.MaxLength > 0 && size > pp.MaxLength {
+		violations = append(violations, PasswordTooLong)
+	}
+
+	var lower, upper, digit, symbol bool
+
+	for _, c := range password {
+		switch {
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsDigit(c):
+			digit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			symbol = true
+		}
+	}
+
+	if pp.RequireLower && !lower {
+		violations = append(violations, PasswordMissingLower)
+	}
+
+	if pp.RequireUpper && !upper {
+		violations = append(violations, PasswordMissingUpper)
+	}
+
+	if pp.RequireDigit && !digit {
+		violations = append(violations, PasswordMissingDigit)
+	}
+
+	if pp.RequireSymbol && !symbol {
+		violations = append(violations, PasswordMissingSymbol)
+	}
+
+	if pp.Banned != nil && pp.Banned.Contains(strings.ToLower(password)) {
+		violations = append(violations, PasswordBanned)
+	}
+
+	if pp.MaxSimilarity > 0 {
+		for _, input := range userInputs {
+			if PasswordSimilarity(password, input) > pp.MaxSimilarity {
+				violations = append(violations, PasswordSimilar)
+
+				break
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// PasswordSimilarity 忽略大小写的相似度(0~1), 互相包含视为完全相似
+func PasswordSimilarity(password string, input string) float64 {
+	a := []rune(strings.ToLower(password))
+	b := []rune(strings.ToLower(input))
+
+	if len(a) == 0 || len(b) < 3 {
+		return 0
+	}
+
+	if strings.Contains(string(a), string(b)) || strings.Contains(string(b), string(a)) {
+		return 1
+	}
+
+	// Levenshtein距离
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			curr[j] = prev[j-1] + cost
+
+			if v := prev[j] + 1; v < curr[j] {
+				curr[j] = v
+			}
+
+			if v := curr[j-1] + 1; v < curr[j] {
+				curr[j] = v
+			}
+		}
+
+		prev, curr = curr, prev
+	}
+
+	size := len(a)
+
+	if len(b) > size {
+		size = len(b)
+	}
+
+	return 1 - float64(prev[len(b)])/float64(size)
+}
+
+// LoadBannedPasswords 读取禁用密码列表, 每行一个
+func LoadBannedPasswords(r io.Reader) (mapset.Set[string], error) {
+	data := mapset.NewSet[string]()
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if v := strings.TrimSpace(scanner.Text()); v != "" {
+			data.Add(strings.ToLower(v))
+		}
+	}
+
+	return data, scanner.Err()
+}
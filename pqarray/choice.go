@@ -10,6 +10,8 @@ import (
 type ChoiceArray struct {
 	Choices []datatype.ChoiceType
 	Meta    []datatype.Choice
+	// 存储方式, 字符串代码时保存为text[]
+	Storage datatype.ChoiceStorage
 }
 
 // GORM
@@ -18,12 +20,27 @@ func (a IntArray) ChoiceArray() string {
 }
 
 func (a *ChoiceArray) Scan(value any) error {
+	if a.Storage == datatype.ChoiceStoreKey {
+		var objs pq.StringArray
+
+		if err := objs.Scan(value); err == nil {
+			for _, v := range objs {
+				choice := datatype.ChoiceType{Meta: a.Meta, Storage: a.Storage}
+				choice.SetKey(v)
+
+				a.Choices = append(a.Choices, choice)
+			}
+		}
+
+		return nil
+	}
+
 	var objs pq.Int64Array
 
 	if err := objs.Scan(value); err == nil {
 		for _, v := range objs {
-			choice := datatype.ChoiceType{Meta: a.Meta}
-			choice.Update(uint8(v))
+			choice := datatype.ChoiceType{Meta: a.Meta, Storage: a.Storage}
+			choice.Update(v)
 
 			a.Choices = append(a.Choices, choice)
 		}
@@ -33,17 +50,27 @@ func (a *ChoiceArray) Scan(value any) error {
 }
 
 func (a ChoiceArray) Value() (driver.Value, error) {
+	if a.Storage == datatype.ChoiceStoreKey {
+		var v []string
+
+		for _, choice := range a.Choices {
+			v = append(v, choice.Choice.Key)
+		}
+
+		return pq.StringArray(v).Value()
+	}
+
 	var v []int64
 
 	for _, choice := range a.Choices {
-		v = append(v, int64(choice.Choice.Code))
+		v = append(v, choice.Choice.Code)
 	}
 
 	return pq.Int64Array(v).Value()
 }
 
-func (a ChoiceArray) Array() []uint8 {
-	var v []uint8
+func (a ChoiceArray) Array() []int64 {
+	var v []int64
 
 	for _, choice := range a.Choices {
 		v = append(v, choice.Choice.Code)
@@ -51,3 +78,13 @@ func (a ChoiceArray) Array() []uint8 {
 
 	return v
 }
+
+func (a ChoiceArray) Keys() []string {
+	var v []string
+
+	for _, choice := range a.Choices {
+		v = append(v, choice.Choice.Key)
+	}
+
+	return v
+}
@@ -0,0 +1,318 @@
+package datatype
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSubjectShredded = errors.New("datatype: subject key has been shredded")
+)
+
+// 数据主体密钥存储
+type SubjectKeyStore interface {
+	// 获取主体的数据密钥, create为true且不存在时创建
+	Key(ctx context.Context, subject string, create bool) (keyID string, key []byte, err error)
+	// 销毁主体的数据密钥
+	Destroy(ctx context.Context, subject string) error
+}
+
+// ---------------------------------------------------------
+//
+//  ShreddingEncryptService
+//
+// ---------------------------------------------------------
+
+// 按数据主体加密, 销毁主体密钥后该主体的所有密文无法解密
+//
+// 主体由EncryptContext.Subject指定, 通常使用encrypt_subject序列化器从GORM行中获取;
+// 无主体的值使用Service加密, Service应支持二进制数据及关联数据(如AESEncryptService)
+type ShreddingEncryptService struct {
+	// 主体密钥存储
+	Keys SubjectKeyStore
+	// 无主体时使用的加密服务
+	Service EncryptService
+}
+
+func (es ShreddingEncryptService) Encode(value string) string {
+	v, _ := es.TryEncode(value)
+
+	return v
+}
+
+func (es ShreddingEncryptService) Decode(value string) string {
+	v, _ := es.TryDecode(value)
+
+	return v
+}
+
+func (es ShreddingEncryptService) Mask(value string) string {
+	return DefaultEncryptService{}.Mask(value)
+}
+
+func (es ShreddingEncryptService) TryEncode(value string) (string, error) {
+	return encryptServiceEncode(es.Service, value)
+}
+
+func (es ShreddingEncryptService) TryDecode(value string) (string, error) {
+	if strings.HasPrefix(value, "$subj$") {
+		return "", ErrEncryptSubject
+	}
+
+	return encryptServiceDecode(es.Service, value)
+}
+
+// EncodeContext 密文格式: $subj$<KeyID>$<base64(nonce+ciphertext)>
+func (es ShreddingEncryptService) EncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	if ec.Subject == "" {
+		return es.fallback(ctx, ec, value)
+	}
+
+	keyID, key, err := es.Keys.Key(ctx, ec.Subject, true)
+
+	if err != nil {
+		return "", err
+	}
+
+	data, err := aesSeal(key, value, ec.AssociatedData())
+
+	if err != nil {
+		return "", err
+	}
+
+	return "$subj$" + keyID + "$" + base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func (es ShreddingEncryptService) DecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error) {
+	if !strings.HasPrefix(value, "$subj$") {
+		return encryptServiceDecodeContext(ctx, es.Service, ec, value)
+	}
+
+	if ec.Subject == "" {
+		return nil, ErrEncryptSubject
+	}
+
+	parts := strings.Split(value, "$")
+
+	if len(parts) != 4 {
+		return nil, ErrEncryptCorrupted
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[3])
+
+	if err != nil {
+		return nil, ErrEncryptCorrupted
+	}
+
+	keyID, key, err := es.Keys.Key(ctx, ec.Subject, false)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// 密钥已销毁后重新创建的密钥不能解密旧密文
+	if keyID != parts[2] {
+		return nil, ErrSubjectShredded
+	}
+
+	return aesOpen(key, data, ec.AssociatedData())
+}
+
+// Shred 销毁主体密钥
+func (es ShreddingEncryptService) Shred(ctx context.Context, subject string) error {
+	return es.Keys.Destroy(ctx, subject)
+}
+
+func (es ShreddingEncryptService) fallback(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	return encryptServiceEncodeContext(ctx, es.Service, ec, value)
+}
+
+// newSubjectKey
+func newSubjectKey() (string, []byte, error) {
+	id := make([]byte, 8)
+	key := make([]byte, 32)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, err
+	}
+
+	return hex.EncodeToString(id), key, nil
+}
+
+// ---------------------------------------------------------
+//
+//  MemorySubjectKeyStore
+//
+// ---------------------------------------------------------
+
+type memorySubjectKey struct {
+	id  string
+	key []byte
+}
+
+// 内存主体密钥存储, 用于测试
+type MemorySubjectKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]memorySubjectKey
+}
+
+func (s *MemorySubjectKeyStore) Key(ctx context.Context, subject string, create bool) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.keys[subject]; ok {
+		return v.id, v.key, nil
+	}
+
+	if !create {
+		return "", nil, ErrSubjectShredded
+	}
+
+	id, key, err := newSubjectKey()
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if s.keys == nil {
+		s.keys = map[string]memorySubjectKey{}
+	}
+
+	s.keys[subject] = memorySubjectKey{id: id, key: key}
+
+	return id, key, nil
+}
+
+func (s *MemorySubjectKeyStore) Destroy(ctx context.Context, subject string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, subject)
+
+	return nil
+}
+
+// ---------------------------------------------------------
+//
+//  GormSubjectKeyStore
+//
+// ---------------------------------------------------------
+
+type SubjectKeyRecord struct {
+	Subject   string `gorm:"primaryKey;size:128"`
+	KeyID     string `gorm:"size:32;not null"`
+	Key       []byte `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// 基于GORM数据表的主体密钥存储, 配置KMS时密钥以包装形式保存
+type GormSubjectKeyStore struct {
+	DB *gorm.DB
+	// 表名, 默认subject_key_records
+	Table string
+	// 密钥管理服务
+	KMS KMS
+	// 解包密钥缓存, 多实例部署时过期时间即为销毁生效的最长延迟
+	Cache *cache.Cache
+}
+
+// Migrate 创建密钥表
+func (s GormSubjectKeyStore) Migrate() error {
+	return s.db(context.Background()).AutoMigrate(&SubjectKeyRecord{})
+}
+
+func (s GormSubjectKeyStore) Key(ctx context.Context, subject string, create bool) (string, []byte, error) {
+	if s.Cache != nil {
+		if v, ok := s.Cache.Get(subject); ok {
+			if key, ok := v.(memorySubjectKey); ok {
+				return key.id, key.key, nil
+			}
+		}
+	}
+
+	var record SubjectKeyRecord
+
+	err := s.db(ctx).Where("subject = ?", subject).Take(&record).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !create {
+			return "", nil, ErrSubjectShredded
+		}
+
+		if err := s.create(ctx, subject); err != nil {
+			return "", nil, err
+		}
+
+		// 并发创建时以数据库中的记录为准
+		err = s.db(ctx).Where("subject = ?", subject).Take(&record).Error
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	key := record.Key
+
+	if s.KMS != nil {
+		if key, err = s.KMS.UnwrapKey(record.Key); err != nil {
+			return "", nil, err
+		}
+	}
+
+	if s.Cache != nil {
+		s.Cache.SetDefault(subject, memorySubjectKey{id: record.KeyID, key: key})
+	}
+
+	return record.KeyID, key, nil
+}
+
+func (s GormSubjectKeyStore) Destroy(ctx context.Context, subject string) error {
+	if s.Cache != nil {
+		s.Cache.Delete(subject)
+	}
+
+	return s.db(ctx).Where("subject = ?", subject).Delete(&SubjectKeyRecord{}).Error
+}
+
+func (s GormSubjectKeyStore) create(ctx context.Context, subject string) error {
+	id, key, err := newSubjectKey()
+
+	if err != nil {
+		return err
+	}
+
+	if s.KMS != nil {
+		if key, err = s.KMS.WrapKey(key); err != nil {
+			return err
+		}
+	}
+
+	return s.db(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SubjectKeyRecord{Subject: subject, KeyID: id, Key: key}).
+		Error
+}
+
+func (s GormSubjectKeyStore) db(ctx context.Context) *gorm.DB {
+	db := s.DB.Session(&gorm.Session{NewDB: true, Context: ctx})
+
+	if s.Table != "" {
+		db = db.Table(s.Table)
+	}
+
+	return db
+}
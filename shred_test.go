@@ -0,0 +1,45 @@
+package datatype
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestShreddingEncryptService(t *testing.T) {
+	es := ShreddingEncryptService{Keys: &MemorySubjectKeyStore{}}
+	ec := EncryptContext{Table: "users", Column: "phone", RowID: "1", Subject: "user-1"}
+
+	data, err := es.EncodeContext(context.Background(), ec, []byte("13800138000"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := es.DecodeContext(context.Background(), ec, data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(value) != "13800138000" {
+		t.Fatalf("got %q, want %q", value, "13800138000")
+	}
+
+	if err := es.Shred(context.Background(), ec.Subject); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := es.DecodeContext(context.Background(), ec, data); !errors.Is(err, ErrSubjectShredded) {
+		t.Fatalf("after shred got %v, want %v", err, ErrSubjectShredded)
+	}
+
+	// 为同一主体重新创建的密钥不能解密旧密文
+	if _, _, err := es.Keys.Key(context.Background(), ec.Subject, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := es.DecodeContext(context.Background(), ec, data); !errors.Is(err, ErrSubjectShredded) {
+		t.Fatalf("after recreate got %v, want %v", err, ErrSubjectShredded)
+	}
+}
@@ -0,0 +1,226 @@
+package datatype
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSecretToken = errors.New("datatype: invalid secret token")
+)
+
+type SecretTokenConfig struct {
+	// 默认前缀
+	Prefix string
+	// 查找前缀长度(字节)
+	LookupSize int
+	// 密钥长度(字节)
+	SecretSize int
+	// HMAC密钥, 为空时使用SHA-256
+	Key []byte
+}
+
+var SecretTokenOptions = SecretTokenConfig{
+	Prefix:     "sk",
+	LookupSize: 5,
+	SecretSize: 32,
+}
+
+// API密钥, 令牌格式为<prefix>_<lookup>_<secret>, 仅保存查找前缀及哈希
+type SecretToken struct {
+	lookup string
+	hash   []byte
+}
+
+// NewSecretToken 生成令牌, 明文仅在此时返回
+func NewSecretToken(prefix string) (SecretToken, string, error) {
+	if prefix == "" {
+		prefix = SecretTokenOptions.Prefix
+	}
+
+	if strings.Contains(prefix, "_") || !secretTokenValid(prefix) {
+		return SecretToken{}, "", ErrSecretToken
+	}
+
+	lookup, err := secretTokenRandom(SecretTokenOptions.LookupSize)
+
+	if err != nil {
+		return SecretToken{}, "", err
+	}
+
+	secret, err := secretTokenRandom(SecretTokenOptions.SecretSize)
+
+	if err != nil {
+		return SecretToken{}, "", err
+	}
+
+	token := prefix + "_" + lookup + "_" + secret
+
+	return SecretToken{lookup: prefix + "_" + lookup, hash: secretTokenHash(token)}, token, nil
+}
+
+// ParseSecretToken 解析明文令牌, 用于查询及校验
+func ParseSecretToken(token string) (SecretToken, error) {
+	parts := strings.Split(token, "_")
+
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return SecretToken{}, ErrSecretToken
+	}
+
+	if !secretTokenValid(token) {
+		return SecretToken{}, ErrSecretToken
+	}
+
+	return SecretToken{lookup: parts[0] + "_" + parts[1], hash: secretTokenHash(token)}, nil
+}
+
+// IsZero
+func (t SecretToken) IsZero() bool {
+	return t.lookup == ""
+}
+
+// Lookup 查找前缀, 可安全展示
+func (t SecretToken) Lookup() string {
+	return t.lookup
+}
+
+// Compare 常量时间比较
+func (t SecretToken) Compare(token string) bool {
+	v, err := ParseSecretToken(token)
+
+	if err != nil || len(t.hash) == 0 {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(v.lookup), []byte(t.lookup))&subtle.ConstantTimeCompare(v.hash, t.hash) == 1
+}
+
+// GORM
+func (t *SecretToken) Scan(value any) error {
+	var s string
+
+	switch v := value.(type) {
+	case nil:
+		*t = SecretToken{}
+
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into SecretToken", value)
+	}
+
+	if s == "" {
+		*t = SecretToken{}
+
+		return nil
+	}
+
+	lookup, hash, ok := strings.Cut(s, "$")
+
+	if !ok {
+		return ErrSecretToken
+	}
+
+	data, err := hex.DecodeString(hash)
+
+	if err != nil {
+		return ErrSecretToken
+	}
+
+	*t = SecretToken{lookup: lookup, hash: data}
+
+	return nil
+}
+
+// Value 存储格式: <prefix>_<lookup>$<hex hash>
+func (t SecretToken) Value() (driver.Value, error) {
+	if t.lookup == "" {
+		return nil, nil
+	}
+
+	return t.lookup + "$" + hex.EncodeToString(t.hash), nil
+}
+
+// JSON
+func (t SecretToken) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// String
+func (t SecretToken) String() string {
+	if t.lookup == "" {
+		return ""
+	}
+
+	return t.lookup + "_" + passwordRedacted
+}
+
+// SecretTokenQuery 按查找前缀查询, 查询结果需再使用Compare校验
+//
+//	db.Where(datatype.SecretTokenQuery("token", token)).First(&key)
+func SecretTokenQuery(column string, token string) clause.Expression {
+	v, err := ParseSecretToken(token)
+
+	if err != nil {
+		return clause.Expr{SQL: "1 = 0"}
+	}
+
+	return clause.Expr{
+		SQL:  "? LIKE ? ESCAPE '!'",
+		Vars: []any{clause.Column{Name: column}, secretTokenEscapeLike(v.lookup) + "$%"},
+	}
+}
+
+// secretTokenValid 前缀只允许字母、数字及'-', 查找前缀及密钥为小写base32
+func secretTokenValid(value string) bool {
+	for _, c := range value {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// secretTokenEscapeLike 转义LIKE通配符, 查找前缀中的'_'需按字面匹配
+func secretTokenEscapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// secretTokenHash
+func secretTokenHash(token string) []byte {
+	if len(SecretTokenOptions.Key) > 0 {
+		mac := hmac.New(sha256.New, SecretTokenOptions.Key)
+		mac.Write([]byte(token))
+
+		return mac.Sum(nil)
+	}
+
+	sum := sha256.Sum256([]byte(token))
+
+	return sum[:]
+}
+
+// secretTokenRandom
+func secretTokenRandom(size int) (string, error) {
+	data := make([]byte, size)
+
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(totpEncoding.EncodeToString(data)), nil
+}
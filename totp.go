@@ -0,0 +1,327 @@
+package datatype
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"database/sql/driver"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrTOTPSecret = errors.New("datatype: invalid totp secret")
+)
+
+type TOTPConfig struct {
+	// 发行方
+	Issuer string
+	// 验证码位数
+	Digits int
+	// 时间步长
+	Period time.Duration
+	// 允许前后偏移的时间步数
+	Skew int
+	// 哈希算法: SHA1, SHA256, SHA512
+	Algorithm string
+	// 密钥长度(字节)
+	SecretSize int
+}
+
+var TOTPOptions = TOTPConfig{
+	Digits:     6,
+	Period:     30 * time.Second,
+	Skew:       1,
+	Algorithm:  "SHA1",
+	SecretSize: 20,
+}
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// HOTP RFC 4226
+func HOTP(secret []byte, counter uint64, digits int, algorithm string) string {
+	var h func() hash.Hash
+
+	switch strings.ToUpper(algorithm) {
+	case "SHA256":
+		h = sha256.New
+	case "SHA512":
+		h = sha512.New
+	default:
+		h = sha1.New
+	}
+
+	mac := hmac.New(h, secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
+// ---------------------------------------------------------
+//
+//  TOTPSecret
+//
+// ---------------------------------------------------------
+
+// TOTP密钥, 使用EncryptOptions.Service加密存储, 同时保存最后使用的时间步防止重放
+//
+// 加密服务须实现BytesEncryptService(如AESEncryptService), 否则保存时返回ErrEncryptBytes
+type TOTPSecret struct {
+	secret []byte
+	step   int64
+}
+
+// NewTOTPSecret
+func NewTOTPSecret() (TOTPSecret, error) {
+	secret := make([]byte, TOTPOptions.SecretSize)
+
+	if _, err := rand.Read(secret); err != nil {
+		return TOTPSecret{}, err
+	}
+
+	return TOTPSecret{secret: secret}, nil
+}
+
+// ParseTOTPSecret 解析Base32密钥
+func ParseTOTPSecret(secret string) (TOTPSecret, error) {
+	v, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "=")))
+
+	if err != nil || len(v) == 0 {
+		return TOTPSecret{}, ErrTOTPSecret
+	}
+
+	return TOTPSecret{secret: v}, nil
+}
+
+// IsZero
+func (t TOTPSecret) IsZero() bool {
+	return len(t.secret) == 0
+}
+
+// Secret Base32密钥
+func (t TOTPSecret) Secret() string {
+	return totpEncoding.EncodeToString(t.secret)
+}
+
+// URI otpauth://totp/Issuer:account?secret=...
+func (t TOTPSecret) URI(account string) string {
+	label := account
+
+	if TOTPOptions.Issuer != "" {
+		label = TOTPOptions.Issuer + ":" + account
+	}
+
+	query := url.Values{}
+	query.Set("secret", t.Secret())
+	query.Set("algorithm", strings.ToUpper(TOTPOptions.Algorithm))
+	query.Set("digits", strconv.Itoa(TOTPOptions.Digits))
+	query.Set("period", strconv.Itoa(int(TOTPOptions.Period/time.Second)))
+
+	if TOTPOptions.Issuer != "" {
+		query.Set("issuer", TOTPOptions.Issuer)
+	}
+
+	return "otpauth://totp/" + url.PathEscape(label) + "?" + query.Encode()
+}
+
+// Code 生成指定时间的验证码
+func (t TOTPSecret) Code(now time.Time) string {
+	return HOTP(t.secret, uint64(now.Unix()/int64(TOTPOptions.Period/time.Second)), TOTPOptions.Digits, TOTPOptions.Algorithm)
+}
+
+// Verify 校验当前验证码, 成功后记录时间步, 需要保存以防止重放
+func (t *TOTPSecret) Verify(code string) bool {
+	return t.VerifyAt(code, time.Now())
+}
+
+// VerifyAt RFC 6238, 允许TOTPOptions.Skew个时间步的偏移, 已使用的时间步不能再次使用
+func (t *TOTPSecret) VerifyAt(code string, now time.Time) bool {
+	if len(t.secret) == 0 || len(code) != TOTPOptions.Digits {
+		return false
+	}
+
+	current := now.Unix() / int64(TOTPOptions.Period/time.Second)
+	matched := int64(-1)
+
+	for step := current - int64(TOTPOptions.Skew); step <= current+int64(TOTPOptions.Skew); step++ {
+		if step <= t.step || step < 0 {
+			continue
+		}
+
+		v := HOTP(t.secret, uint64(step), TOTPOptions.Digits, TOTPOptions.Algorithm)
+
+		if subtle.ConstantTimeCompare([]byte(v), []byte(code)) == 1 && matched < 0 {
+			matched = step
+		}
+	}
+
+	if matched < 0 {
+		return false
+	}
+
+	t.step = matched
+
+	return true
+}
+
+// GORM
+func (t *TOTPSecret) Scan(value any) error {
+	var s string
+
+	switch v := value.(type) {
+	case nil:
+		*t = TOTPSecret{}
+
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("datatype: cannot scan %T into TOTPSecret", value)
+	}
+
+	if s == "" {
+		*t = TOTPSecret{}
+
+		return nil
+	}
+
+	v, err := EncryptDecodeBytes(s)
+
+	if err != nil {
+		return err
+	}
+
+	secret, step, _ := strings.Cut(string(v), ":")
+
+	data, err := ParseTOTPSecret(secret)
+
+	if err != nil {
+		return err
+	}
+
+	if step != "" {
+		if data.step, err = strconv.ParseInt(step, 10, 64); err != nil {
+			return ErrTOTPSecret
+		}
+	}
+
+	*t = data
+
+	return nil
+}
+
+func (t TOTPSecret) Value() (driver.Value, error) {
+	if len(t.secret) == 0 {
+		return nil, nil
+	}
+
+	// 密钥为base32文本, 只能使用认证加密保存
+	return EncryptEncodeBytes([]byte(t.Secret() + ":" + strconv.FormatInt(t.step, 10)))
+}
+
+// JSON
+func (t TOTPSecret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(passwordRedacted)
+}
+
+// String
+func (t TOTPSecret) String() string {
+	return passwordRedacted
+}
+
+// ---------------------------------------------------------
+//
+//  RecoveryCodes
+//
+// ---------------------------------------------------------
+
+// 恢复码哈希, 与Password使用相同的哈希算法, 存储方式同PasswordHistory
+type RecoveryCodes [][]byte
+
+// GenerateRecoveryCodes 生成n个恢复码, 返回明文(仅展示一次)及哈希
+func GenerateRecoveryCodes(n int) ([]string, RecoveryCodes, error) {
+	codes := make([]string, n)
+	hashes := make(RecoveryCodes, n)
+
+	for i := 0; i < n; i++ {
+		data := make([]byte, 5)
+
+		if _, err := rand.Read(data); err != nil {
+			return nil, nil, err
+		}
+
+		code := strings.ToLower(totpEncoding.EncodeToString(data))
+
+		var p Password
+
+		if err := p.rehash(code); err != nil {
+			return nil, nil, err
+		}
+
+		codes[i] = code[:4] + "-" + code[4:]
+		hashes[i] = p.hash
+	}
+
+	return codes, hashes, nil
+}
+
+// Use 校验恢复码, 成功后移除该恢复码, 需要保存
+func (r *RecoveryCodes) Use(code string) bool {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	for i, hash := range *r {
+		if ok, _ := passwordVerify(hash, code); ok {
+			*r = append((*r)[:i:i], (*r)[i+1:]...)
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// GORM
+func (r RecoveryCodes) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return PasswordHistory(r).GormDBDataType(db, field)
+}
+
+func (r RecoveryCodes) GormValue(ctx context.Context, db *gorm.DB) clause.Expr {
+	return PasswordHistory(r).GormValue(ctx, db)
+}
+
+func (r *RecoveryCodes) Scan(value any) error {
+	return (*PasswordHistory)(r).Scan(value)
+}
+
+// JSON
+func (r RecoveryCodes) MarshalJSON() ([]byte, error) {
+	return PasswordHistory(r).MarshalJSON()
+}
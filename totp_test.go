@@ -0,0 +1,160 @@
+package datatype
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 4226 附录D
+func TestHOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for i, code := range want {
+		if v := HOTP(secret, uint64(i), 6, "SHA1"); v != code {
+			t.Errorf("HOTP(%d) = %s, want %s", i, v, code)
+		}
+	}
+}
+
+// RFC 6238 附录B
+func TestTOTPVectors(t *testing.T) {
+	secrets := map[string][]byte{
+		"SHA1":   []byte("12345678901234567890"),
+		"SHA256": []byte("12345678901234567890123456789012"),
+		"SHA512": []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+
+	cases := []struct {
+		time int64
+		want map[string]string
+	}{
+		{59, map[string]string{"SHA1": "94287082", "SHA256": "46119246", "SHA512": "90693936"}},
+		{1111111109, map[string]string{"SHA1": "07081804", "SHA256": "68084774", "SHA512": "25091201"}},
+		{1111111111, map[string]string{"SHA1": "14050471", "SHA256": "67062674", "SHA512": "99943326"}},
+		{1234567890, map[string]string{"SHA1": "89005924", "SHA256": "91819424", "SHA512": "93441116"}},
+		{2000000000, map[string]string{"SHA1": "69279037", "SHA256": "90698825", "SHA512": "38618901"}},
+		{20000000000, map[string]string{"SHA1": "65353130", "SHA256": "77737706", "SHA512": "47863826"}},
+	}
+
+	for _, c := range cases {
+		for algorithm, want := range c.want {
+			if v := HOTP(secrets[algorithm], uint64(c.time/30), 8, algorithm); v != want {
+				t.Errorf("%s at %d = %s, want %s", algorithm, c.time, v, want)
+			}
+		}
+	}
+}
+
+func TestTOTPVerifyAt(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	period := TOTPOptions.Period
+
+	// 允许TOTPOptions.Skew个时间步的偏移
+	secret := TOTPSecret{secret: []byte("12345678901234567890")}
+
+	if !secret.VerifyAt(secret.Code(now.Add(-period)), now) {
+		t.Error("previous step rejected")
+	}
+
+	secret = TOTPSecret{secret: []byte("12345678901234567890")}
+
+	if !secret.VerifyAt(secret.Code(now.Add(period)), now) {
+		t.Error("next step rejected")
+	}
+
+	secret = TOTPSecret{secret: []byte("12345678901234567890")}
+
+	if secret.VerifyAt(secret.Code(now.Add(-time.Duration(TOTPOptions.Skew+1)*period)), now) {
+		t.Error("code outside skew accepted")
+	}
+
+	// 已使用的时间步及之前的验证码不能再次使用
+	code := secret.Code(now)
+
+	if !secret.VerifyAt(code, now) {
+		t.Fatal("current code rejected")
+	}
+
+	if secret.VerifyAt(code, now) {
+		t.Error("replayed code accepted")
+	}
+
+	if secret.VerifyAt(secret.Code(now.Add(-period)), now) {
+		t.Error("earlier code accepted after use")
+	}
+
+	if !secret.VerifyAt(secret.Code(now.Add(period)), now.Add(period)) {
+		t.Error("next code rejected after use")
+	}
+}
+
+func TestTOTPSecretStorage(t *testing.T) {
+	encryptContextService(t, AESEncryptService{KeyID: "1", Keys: map[string][]byte{"1": make([]byte, 32)}})
+
+	secret, err := NewTOTPSecret()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	if !secret.VerifyAt(secret.Code(now), now) {
+		t.Fatal("current code rejected")
+	}
+
+	v, err := secret.Value()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(v.(string), secret.Secret()) {
+		t.Fatal("secret stored in plaintext")
+	}
+
+	var loaded TOTPSecret
+
+	if err := loaded.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.Secret() != secret.Secret() {
+		t.Fatalf("got secret %s, want %s", loaded.Secret(), secret.Secret())
+	}
+
+	// 保存的时间步同样防止重放
+	if loaded.VerifyAt(secret.Code(now), now) {
+		t.Error("replayed code accepted after reload")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := GenerateRecoveryCodes(2)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(codes) != 2 || len(hashes) != 2 {
+		t.Fatalf("got %d codes and %d hashes, want 2", len(codes), len(hashes))
+	}
+
+	if hashes.Use("0000-0000") {
+		t.Error("unknown code accepted")
+	}
+
+	if !hashes.Use(strings.ToUpper(codes[0])) {
+		t.Fatal("recovery code rejected")
+	}
+
+	if hashes.Use(codes[0]) {
+		t.Error("used recovery code accepted")
+	}
+
+	if len(hashes) != 1 || !hashes.Use(codes[1]) {
+		t.Error("remaining recovery code rejected")
+	}
+}
@@ -0,0 +1,229 @@
+package datatype
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTokenNotFound = errors.New("datatype: token not found in vault")
+)
+
+// 令牌存储
+type TokenVault interface {
+	// 保存令牌及对应的密文, 令牌已存在时忽略
+	Store(ctx context.Context, token string, value string) error
+	// 读取令牌对应的密文
+	Load(ctx context.Context, token string) (string, error)
+}
+
+// 令牌访问事件
+type TokenVaultEvent struct {
+	// tokenize / detokenize
+	Action string
+	Token  string
+	Time   time.Time
+	Err    error
+}
+
+// ---------------------------------------------------------
+//
+//  VaultEncryptService
+//
+// ---------------------------------------------------------
+
+// 令牌化加密服务, 字段中只保存确定性令牌, 真实值加密后保存在令牌库中
+//
+// Encrypt的Scan及Value没有请求上下文, 审计时ctx为context.Background(); 需要识别调用方时使用serializer:encrypt
+//
+//	CardNo datatype.Encrypt `gorm:"serializer:encrypt"`
+type VaultEncryptService struct {
+	// 令牌库
+	Vault TokenVault
+	// 生成令牌的HMAC密钥, 不能为空
+	Key []byte
+	// 令牌库中真实值的加密服务
+	Service EncryptService
+	// 审计
+	Audit func(ctx context.Context, event TokenVaultEvent)
+}
+
+func (es VaultEncryptService) Encode(value string) string {
+	v, _ := es.TryEncode(value)
+
+	return v
+}
+
+func (es VaultEncryptService) Decode(value string) string {
+	v, _ := es.TryDecode(value)
+
+	return v
+}
+
+func (es VaultEncryptService) Mask(value string) string {
+	return DefaultEncryptService{}.Mask(value)
+}
+
+func (es VaultEncryptService) TryEncode(value string) (string, error) {
+	return es.Tokenize(context.Background(), value)
+}
+
+func (es VaultEncryptService) TryDecode(value string) (string, error) {
+	return es.Detokenize(context.Background(), value)
+}
+
+// EncodeContext 使用serializer:encrypt时由GORM传入请求上下文, 审计可据此识别调用方;
+// 令牌只由值决定以支持等值查询, 不与表、列或行绑定
+func (es VaultEncryptService) EncodeContext(ctx context.Context, ec EncryptContext, value []byte) (string, error) {
+	return es.Tokenize(ctx, string(value))
+}
+
+func (es VaultEncryptService) DecodeContext(ctx context.Context, ec EncryptContext, value string) ([]byte, error) {
+	v, err := es.Detokenize(ctx, value)
+
+	return []byte(v), err
+}
+
+// Token 计算值对应的令牌, 相同的值得到相同的令牌, 可用于等值查询
+func (es VaultEncryptService) Token(value string) string {
+	mac := hmac.New(sha256.New, es.Key)
+	mac.Write([]byte(value))
+
+	return "tok_" + strings.ToLower(totpEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// Tokenize 保存真实值并返回令牌
+func (es VaultEncryptService) Tokenize(ctx context.Context, value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+
+	// 空密钥生成的令牌任何人都可以计算
+	if len(es.Key) == 0 {
+		return "", ErrEncryptKey
+	}
+
+	token := es.Token(value)
+
+	data, err := encryptServiceEncode(es.Service, value)
+
+	if err == nil {
+		err = es.Vault.Store(ctx, token, data)
+	}
+
+	es.audit(ctx, "tokenize", token, err)
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Detokenize 读取令牌对应的真实值, 每次访问都会记录审计
+func (es VaultEncryptService) Detokenize(ctx context.Context, token string) (string, error) {
+	if token == "" {
+		return "", nil
+	}
+
+	if !strings.HasPrefix(token, "tok_") {
+		return "", ErrEncryptCorrupted
+	}
+
+	if len(es.Key) == 0 {
+		return "", ErrEncryptKey
+	}
+
+	data, err := es.Vault.Load(ctx, token)
+
+	var value string
+
+	if err == nil {
+		value, err = encryptServiceDecode(es.Service, data)
+	}
+
+	if err == nil && !hmac.Equal([]byte(es.Token(value)), []byte(token)) {
+		err = ErrEncryptCorrupted
+	}
+
+	es.audit(ctx, "detokenize", token, err)
+
+	if err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+func (es VaultEncryptService) audit(ctx context.Context, action string, token string, err error) {
+	if es.Audit != nil {
+		es.Audit(ctx, TokenVaultEvent{Action: action, Token: token, Time: time.Now(), Err: err})
+	}
+}
+
+// ---------------------------------------------------------
+//
+//  GormTokenVault
+//
+// ---------------------------------------------------------
+
+type TokenVaultRecord struct {
+	Token     string `gorm:"primaryKey;size:64"`
+	Value     string `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// 基于GORM数据表的令牌库
+type GormTokenVault struct {
+	DB *gorm.DB
+	// 表名, 默认token_vault_records
+	Table string
+}
+
+// Migrate 创建令牌表
+func (v GormTokenVault) Migrate() error {
+	return v.db(context.Background()).AutoMigrate(&TokenVaultRecord{})
+}
+
+func (v GormTokenVault) Store(ctx context.Context, token string, value string) error {
+	return v.db(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&TokenVaultRecord{Token: token, Value: value}).
+		Error
+}
+
+func (v GormTokenVault) Load(ctx context.Context, token string) (string, error) {
+	var record TokenVaultRecord
+
+	if err := v.db(ctx).Where("token = ?", token).Take(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrTokenNotFound
+		}
+
+		return "", err
+	}
+
+	return record.Value, nil
+}
+
+// Delete 删除令牌, 所有引用该令牌的字段将无法还原
+func (v GormTokenVault) Delete(ctx context.Context, token string) error {
+	return v.db(ctx).Where("token = ?", token).Delete(&TokenVaultRecord{}).Error
+}
+
+func (v GormTokenVault) db(ctx context.Context) *gorm.DB {
+	db := v.DB.Session(&gorm.Session{NewDB: true, Context: ctx})
+
+	if v.Table != "" {
+		db = db.Table(v.Table)
+	}
+
+	return db
+}
@@ -0,0 +1,77 @@
+package datatype
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+type vaultTestStore map[string]string
+
+func (v vaultTestStore) Store(ctx context.Context, token string, value string) error {
+	v[token] = value
+
+	return nil
+}
+
+func (v vaultTestStore) Load(ctx context.Context, token string) (string, error) {
+	if value, ok := v[token]; ok {
+		return value, nil
+	}
+
+	return "", ErrTokenNotFound
+}
+
+type vaultTestKey struct{}
+
+type vaultTestCard struct {
+	ID     int64
+	CardNo Encrypt `gorm:"serializer:encrypt"`
+}
+
+// 序列化器传入的请求上下文到达审计
+func TestVaultEncryptServiceAuditContext(t *testing.T) {
+	var callers []any
+
+	encryptContextService(t, VaultEncryptService{
+		Vault:   vaultTestStore{},
+		Key:     []byte("key"),
+		Service: AESEncryptService{KeyID: "1", Keys: map[string][]byte{"1": make([]byte, 32)}},
+		Audit: func(ctx context.Context, event TokenVaultEvent) {
+			callers = append(callers, ctx.Value(vaultTestKey{}))
+		},
+	})
+
+	s, err := schema.Parse(&vaultTestCard{}, &sync.Map{}, schema.NamingStrategy{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), vaultTestKey{}, "alice")
+	field := s.LookUpField("CardNo")
+
+	card := vaultTestCard{ID: 1, CardNo: "4111111111111111"}
+	token, err := EncryptSerializer{}.Value(ctx, field, reflect.ValueOf(&card).Elem(), card.CardNo)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded vaultTestCard
+
+	if err := (EncryptSerializer{}).Scan(ctx, field, reflect.ValueOf(&loaded).Elem(), token); err != nil {
+		t.Fatal(err)
+	}
+
+	if loaded.CardNo != card.CardNo {
+		t.Fatalf("got %q, want %q", loaded.CardNo, card.CardNo)
+	}
+
+	if want := []any{"alice", "alice"}; !reflect.DeepEqual(callers, want) {
+		t.Errorf("audit callers %v, want %v", callers, want)
+	}
+}